@@ -0,0 +1,87 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus-community/stackdriver_exporter/collectors"
+)
+
+const cardinalityPath = "/debug/cardinality"
+
+var cardinalityTemplate = template.Must(template.New("cardinality").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Stackdriver Exporter cardinality</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Cardinality of the last scrape</h1>
+<p><a href="?format=json">JSON</a></p>
+{{range .}}
+<h2>{{.ProjectID}} {{range .Prefixes}}<code>{{.}}</code> {{end}}</h2>
+<p>Scraped at {{.ScrapeTime.Format "2006-01-02T15:04:05Z07:00"}}, {{.Series}} series.</p>
+<table>
+<tr><th>Metric</th><th>Series</th><th>Top label keys (distinct values)</th></tr>
+{{range .Metrics}}<tr><td><code>{{.Name}}</code></td><td class="num">{{.Series}}</td><td>{{range .Labels}}{{.Name}} ({{.DistinctValues}}) {{end}}</td></tr>
+{{end}}</table>
+<table>
+<tr><th>Descriptor</th><th>Time series API calls</th></tr>
+{{range .Descriptors}}<tr><td><code>{{.Type}}</code></td><td class="num">{{.APICalls}}</td></tr>
+{{end}}{{range $prefix, $calls := .DescriptorListCalls}}<tr><td>descriptor list <code>{{$prefix}}</code></td><td class="num">{{$calls}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>No scrape has completed yet.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// cardinalityHandler serves the cardinality statistics of the last scrape of
+// each collector as HTML, or as JSON with ?format=json or an
+// application/json Accept header.
+type cardinalityHandler struct {
+	runtime *collectors.Runtime
+	logger  *slog.Logger
+}
+
+func (h *cardinalityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stats := h.runtime.CardinalityStats()
+	if stats == nil {
+		stats = []*collectors.CardinalityStats{}
+	}
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			h.logger.Error("error encoding cardinality stats", "err", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := cardinalityTemplate.Execute(w, stats); err != nil {
+		h.logger.Error("error rendering cardinality stats", "err", err)
+	}
+}
@@ -14,6 +14,7 @@
 package collectors
 
 import (
+	"slices"
 	"sync"
 	"time"
 
@@ -147,3 +148,73 @@ func (c *collectorCache) removeExpired() {
 		}
 	}
 }
+
+// trackedCollectors remembers the latest collector built for each
+// (project, prefix-filter) key so their scrape statistics can be reported.
+// Entries expire ttl after their collector was last used, so arbitrary
+// prefix filters sent by clients do not accumulate.
+type trackedCollectors struct {
+	collectors map[string]*trackedCollector
+	lock       sync.Mutex
+	ttl        time.Duration
+}
+
+type trackedCollector struct {
+	collector *MonitoringCollector
+	expiry    time.Time
+}
+
+func newTrackedCollectors(ttl time.Duration) *trackedCollectors {
+	return &trackedCollectors{collectors: make(map[string]*trackedCollector), ttl: ttl}
+}
+
+// Store tracks collector under key, or refreshes its expiry, and forgets
+// expired collectors.
+func (t *trackedCollectors) Store(key string, collector *MonitoringCollector) {
+	now := time.Now()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for k, entry := range t.collectors {
+		if now.After(entry.expiry) {
+			delete(t.collectors, k)
+		}
+	}
+	t.collectors[key] = &trackedCollector{collector: collector, expiry: now.Add(t.ttl)}
+}
+
+// retainTargets forgets the collectors of projects, folders and
+// organizations not in targets.
+func (t *trackedCollectors) retainTargets(targets []string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for key, entry := range t.collectors {
+		if !slices.Contains(targets, entry.collector.projectID) {
+			delete(t.collectors, key)
+		}
+	}
+}
+
+func (t *trackedCollectors) CardinalityStats() []*CardinalityStats {
+	now := time.Now()
+	t.lock.Lock()
+	keys := make([]string, 0, len(t.collectors))
+	for key, entry := range t.collectors {
+		if now.Before(entry.expiry) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	cs := make([]*MonitoringCollector, 0, len(keys))
+	for _, key := range keys {
+		cs = append(cs, t.collectors[key].collector)
+	}
+	t.lock.Unlock()
+
+	var out []*CardinalityStats
+	for _, c := range cs {
+		if stats := c.LastCardinalityStats(); stats != nil {
+			out = append(out, stats)
+		}
+	}
+	return out
+}
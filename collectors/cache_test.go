@@ -127,3 +127,26 @@ func TestCollectorCache(t *testing.T) {
 		}
 	})
 }
+
+func TestTrackedCollectorsExpire(t *testing.T) {
+	t.Parallel()
+
+	ttl := 100 * time.Millisecond
+	tracked := newTrackedCollectors(ttl)
+	stale := &MonitoringCollector{projectID: "p", lastCardinalityStats: &CardinalityStats{ProjectID: "p"}}
+	tracked.Store("p-[custom.googleapis.com/a]", stale)
+
+	time.Sleep(2 * ttl)
+	if got := tracked.CardinalityStats(); len(got) != 0 {
+		t.Errorf("CardinalityStats() = %v, want expired collector left out", got)
+	}
+
+	fresh := &MonitoringCollector{projectID: "p", lastCardinalityStats: &CardinalityStats{ProjectID: "p"}}
+	tracked.Store("p-[]", fresh)
+	if len(tracked.collectors) != 1 {
+		t.Errorf("tracked %d collectors, want the expired one removed", len(tracked.collectors))
+	}
+	if got := tracked.CardinalityStats(); len(got) != 1 {
+		t.Errorf("CardinalityStats() returned %d entries, want 1", len(got))
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/stackdriver_exporter/hash"
+)
+
+// topLabelKeys is the number of label keys reported per metric.
+const topLabelKeys = 10
+
+// CardinalityStats describes the series exported by the last scrape of a
+// collector.
+type CardinalityStats struct {
+	ProjectID  string    `json:"project_id"`
+	Prefixes   []string  `json:"prefixes"`
+	ScrapeTime time.Time `json:"scrape_time"`
+	// Series is the total number of series exported.
+	Series int `json:"series"`
+	// Metrics holds one entry per exported metric name, by descending series count.
+	Metrics []MetricCardinality `json:"metrics"`
+	// Descriptors holds the API calls spent per metric descriptor, by descending call count.
+	Descriptors []DescriptorAPICalls `json:"descriptors"`
+	// DescriptorListCalls is the number of metric descriptor list calls per prefix.
+	DescriptorListCalls map[string]int `json:"descriptor_list_calls"`
+}
+
+// MetricCardinality is the cardinality of a single exported metric name.
+type MetricCardinality struct {
+	Name   string `json:"name"`
+	Series int    `json:"series"`
+	// Labels holds the label keys with the most distinct values, by descending distinct value count.
+	Labels []LabelCardinality `json:"labels"`
+}
+
+// LabelCardinality is the number of distinct values of a label key.
+type LabelCardinality struct {
+	Name           string `json:"name"`
+	DistinctValues int    `json:"distinct_values"`
+}
+
+// DescriptorAPICalls is the number of time series list calls made for a
+// metric descriptor.
+type DescriptorAPICalls struct {
+	Type     string `json:"type"`
+	APICalls int    `json:"api_calls"`
+}
+
+// cardinalityRecorder gathers the statistics of a single scrape. A nil
+// recorder records nothing.
+type cardinalityRecorder struct {
+	mu                  sync.Mutex
+	series              map[string]map[uint64]struct{}
+	labelValues         map[string]map[string]map[string]struct{}
+	apiCalls            map[string]int
+	descriptorListCalls map[string]int
+}
+
+func newCardinalityRecorder() *cardinalityRecorder {
+	return &cardinalityRecorder{
+		series:              make(map[string]map[uint64]struct{}),
+		labelValues:         make(map[string]map[string]map[string]struct{}),
+		apiCalls:            make(map[string]int),
+		descriptorListCalls: make(map[string]int),
+	}
+}
+
+func (r *cardinalityRecorder) observe(fqName string, labelKeys, labelValues []string) {
+	if r == nil {
+		return
+	}
+	h := hash.New()
+	for _, i := range sortedLabelIndexes(labelKeys) {
+		h = hash.Add(h, labelKeys[i])
+		h = hash.AddByte(h, hash.SeparatorByte)
+		h = hash.Add(h, labelValues[i])
+		h = hash.AddByte(h, hash.SeparatorByte)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.series[fqName] == nil {
+		r.series[fqName] = make(map[uint64]struct{})
+		r.labelValues[fqName] = make(map[string]map[string]struct{})
+	}
+	r.series[fqName][h] = struct{}{}
+	for i, key := range labelKeys {
+		values := r.labelValues[fqName][key]
+		if values == nil {
+			values = make(map[string]struct{})
+			r.labelValues[fqName][key] = values
+		}
+		values[labelValues[i]] = struct{}{}
+	}
+}
+
+func (r *cardinalityRecorder) addAPICall(descriptorType string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apiCalls[descriptorType]++
+}
+
+func (r *cardinalityRecorder) addDescriptorListCall(prefix string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.descriptorListCalls[prefix]++
+}
+
+func (r *cardinalityRecorder) stats(projectID string, prefixes []string, scrapeTime time.Time) *CardinalityStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := &CardinalityStats{
+		ProjectID:           projectID,
+		Prefixes:            prefixes,
+		ScrapeTime:          scrapeTime,
+		Metrics:             make([]MetricCardinality, 0, len(r.series)),
+		Descriptors:         make([]DescriptorAPICalls, 0, len(r.apiCalls)),
+		DescriptorListCalls: make(map[string]int, len(r.descriptorListCalls)),
+	}
+	for name, series := range r.series {
+		mc := MetricCardinality{Name: name, Series: len(series)}
+		for key, values := range r.labelValues[name] {
+			mc.Labels = append(mc.Labels, LabelCardinality{Name: key, DistinctValues: len(values)})
+		}
+		sort.Slice(mc.Labels, func(i, j int) bool {
+			if mc.Labels[i].DistinctValues != mc.Labels[j].DistinctValues {
+				return mc.Labels[i].DistinctValues > mc.Labels[j].DistinctValues
+			}
+			return mc.Labels[i].Name < mc.Labels[j].Name
+		})
+		if len(mc.Labels) > topLabelKeys {
+			mc.Labels = mc.Labels[:topLabelKeys]
+		}
+		out.Series += mc.Series
+		out.Metrics = append(out.Metrics, mc)
+	}
+	sort.Slice(out.Metrics, func(i, j int) bool {
+		if out.Metrics[i].Series != out.Metrics[j].Series {
+			return out.Metrics[i].Series > out.Metrics[j].Series
+		}
+		return out.Metrics[i].Name < out.Metrics[j].Name
+	})
+	for descriptorType, calls := range r.apiCalls {
+		out.Descriptors = append(out.Descriptors, DescriptorAPICalls{Type: descriptorType, APICalls: calls})
+	}
+	sort.Slice(out.Descriptors, func(i, j int) bool {
+		if out.Descriptors[i].APICalls != out.Descriptors[j].APICalls {
+			return out.Descriptors[i].APICalls > out.Descriptors[j].APICalls
+		}
+		return out.Descriptors[i].Type < out.Descriptors[j].Type
+	})
+	for prefix, calls := range r.descriptorListCalls {
+		out.DescriptorListCalls[prefix] = calls
+	}
+	return out
+}
+
+// sortedLabelIndexes returns the indexes of labelKeys in key order.
+func sortedLabelIndexes(labelKeys []string) []int {
+	idx := make([]int, len(labelKeys))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return labelKeys[idx[a]] < labelKeys[idx[b]] })
+	return idx
+}
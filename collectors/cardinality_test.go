@@ -0,0 +1,80 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCardinalityRecorder(t *testing.T) {
+	t.Parallel()
+
+	r := newCardinalityRecorder()
+	for i := 0; i < 3; i++ {
+		r.observe("stackdriver_gce_instance_cpu", []string{"unit", "instance_id", "zone"}, []string{"s", fmt.Sprintf("%d", i), "us-east1-b"})
+	}
+	// The same series observed twice, with labels in a different order, is counted once.
+	r.observe("stackdriver_gce_instance_cpu", []string{"zone", "unit", "instance_id"}, []string{"us-east1-b", "s", "0"})
+	r.observe("stackdriver_gce_instance_uptime", []string{"unit"}, []string{"s"})
+	r.addAPICall("compute.googleapis.com/instance/cpu/usage_time")
+	r.addAPICall("compute.googleapis.com/instance/cpu/usage_time")
+	r.addAPICall("compute.googleapis.com/instance/uptime")
+	r.addDescriptorListCall("compute.googleapis.com/instance")
+
+	scrapeTime := time.Now()
+	got := r.stats("fake-project", []string{"compute.googleapis.com/instance"}, scrapeTime)
+
+	want := &CardinalityStats{
+		ProjectID:  "fake-project",
+		Prefixes:   []string{"compute.googleapis.com/instance"},
+		ScrapeTime: scrapeTime,
+		Series:     4,
+		Metrics: []MetricCardinality{
+			{
+				Name:   "stackdriver_gce_instance_cpu",
+				Series: 3,
+				Labels: []LabelCardinality{
+					{Name: "instance_id", DistinctValues: 3},
+					{Name: "unit", DistinctValues: 1},
+					{Name: "zone", DistinctValues: 1},
+				},
+			},
+			{
+				Name:   "stackdriver_gce_instance_uptime",
+				Series: 1,
+				Labels: []LabelCardinality{{Name: "unit", DistinctValues: 1}},
+			},
+		},
+		Descriptors: []DescriptorAPICalls{
+			{Type: "compute.googleapis.com/instance/cpu/usage_time", APICalls: 2},
+			{Type: "compute.googleapis.com/instance/uptime", APICalls: 1},
+		},
+		DescriptorListCalls: map[string]int{"compute.googleapis.com/instance": 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNilCardinalityRecorderIsNoop(t *testing.T) {
+	t.Parallel()
+
+	var r *cardinalityRecorder
+	r.observe("metric", []string{"unit"}, []string{"s"})
+	r.addAPICall("compute.googleapis.com/instance/uptime")
+	r.addDescriptorListCall("compute.googleapis.com/instance")
+}
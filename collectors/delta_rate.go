@@ -0,0 +1,75 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+)
+
+// collectDeltaRate exports the newest point of a DELTA series divided by the
+// length of its interval. Distributions are exported as _bucket, _sum and
+// _count gauges, so histogram_quantile can be applied to the bucket rates.
+func (c *MonitoringCollector) collectDeltaRate(tsm *timeSeriesMetrics, timeSeries *monitoring.TimeSeries, layout *BucketLayout, point *monitoring.Point, reportTime time.Time, labelKeys, labelValues []string) error {
+	seconds, err := pointSeconds(point)
+	if err != nil {
+		return err
+	}
+
+	if timeSeries.ValueType != "DISTRIBUTION" {
+		value, ok := pointValue(timeSeries.ValueType, point)
+		if !ok {
+			return fmt.Errorf("unsupported value type %s", timeSeries.ValueType)
+		}
+		tsm.collectConstMetric(buildFQName(timeSeries), reportTime, labelKeys, prometheus.GaugeValue, value/seconds, labelValues)
+		return nil
+	}
+
+	dist := point.Value.DistributionValue
+	buckets, err := c.generateHistogramBuckets(dist, layout)
+	if err != nil {
+		return err
+	}
+	fqName := buildFQName(timeSeries)
+	bucketKeys := append(slices.Clone(labelKeys), "le")
+	for bound, count := range buckets {
+		bucketValues := append(slices.Clone(labelValues), strconv.FormatFloat(bound, 'g', -1, 64))
+		tsm.collectConstMetric(fqName+"_bucket", reportTime, bucketKeys, prometheus.GaugeValue, float64(count)/seconds, bucketValues)
+	}
+	tsm.collectConstMetric(fqName+"_sum", reportTime, labelKeys, prometheus.GaugeValue, dist.Mean*float64(dist.Count)/seconds, labelValues)
+	tsm.collectConstMetric(fqName+"_count", reportTime, labelKeys, prometheus.GaugeValue, float64(dist.Count)/seconds, labelValues)
+	return nil
+}
+
+// pointSeconds returns the length of the interval of a point in seconds.
+func pointSeconds(point *monitoring.Point) (float64, error) {
+	startTime, err := time.Parse(time.RFC3339Nano, point.Interval.StartTime)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing TimeSeries Point interval start time `%s`: %s", point.Interval.StartTime, err)
+	}
+	endTime, err := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing TimeSeries Point interval end time `%s`: %s", point.Interval.EndTime, err)
+	}
+	seconds := endTime.Sub(startTime).Seconds()
+	if seconds <= 0 {
+		return 0, fmt.Errorf("empty point interval ending at %s", point.Interval.EndTime)
+	}
+	return seconds, nil
+}
@@ -0,0 +1,156 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+// gaugeValues reads the gauges sent on ch, keyed by name and le label.
+func gaugeValues(t *testing.T, ch chan prometheus.Metric) map[string]float64 {
+	t.Helper()
+	close(ch)
+	got := make(map[string]float64)
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if m.GetGauge() == nil {
+			t.Fatalf("%s is not a gauge", metric.Desc())
+		}
+		_, name, _ := strings.Cut(metric.Desc().String(), `fqName: "`)
+		name, _, _ = strings.Cut(name, `"`)
+		for _, lp := range m.GetLabel() {
+			if lp.GetName() == "le" {
+				name += "{le=" + lp.GetValue() + "}"
+			}
+		}
+		got[name] = m.GetGauge().GetValue()
+	}
+	return got
+}
+
+func TestDeltaModeFor(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewMonitoringCollector("p", nil, MonitoringCollectorOptions{
+		AggregateDeltas: true,
+		PrefixConfigs: []config.PrefixConfig{
+			{Prefix: "custom.googleapis.com/", DeltaMode: config.DeltaModeRate},
+			{Prefix: "custom.googleapis.com/raw/", DeltaMode: config.DeltaModeGauge},
+		},
+	}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for metricType, want := range map[string]string{
+		"compute.googleapis.com/instance/cpu": config.DeltaModeAggregate,
+		"custom.googleapis.com/requests":      config.DeltaModeRate,
+		"custom.googleapis.com/raw/requests":  config.DeltaModeGauge,
+	} {
+		if got := c.settingsFor(metricType).deltaMode; got != want {
+			t.Errorf("settingsFor(%q).deltaMode = %q, want %q", metricType, got, want)
+		}
+	}
+}
+
+func TestReportTimeSeriesMetricsDeltaRate(t *testing.T) {
+	t.Parallel()
+
+	end := time.Unix(1700000000, 0).UTC()
+	interval := &monitoring.TimeInterval{
+		StartTime: end.Add(-time.Minute).Format(time.RFC3339Nano),
+		EndTime:   end.Format(time.RFC3339Nano),
+	}
+	requests := int64(120)
+	resource := &monitoring.MonitoredResource{Type: "gce_instance"}
+
+	tests := map[string]struct {
+		timeSeries *monitoring.TimeSeries
+		want       map[string]float64
+	}{
+		"scalar": {
+			timeSeries: &monitoring.TimeSeries{
+				Metric:     &monitoring.Metric{Type: "custom.googleapis.com/requests"},
+				Resource:   resource,
+				MetricKind: "DELTA",
+				ValueType:  "INT64",
+				Points:     []*monitoring.Point{{Interval: interval, Value: &monitoring.TypedValue{Int64Value: &requests}}},
+			},
+			want: map[string]float64{"stackdriver_gce_instance_custom_googleapis_com_requests": 2},
+		},
+		"distribution": {
+			timeSeries: &monitoring.TimeSeries{
+				Metric:     &monitoring.Metric{Type: "custom.googleapis.com/latency"},
+				Resource:   resource,
+				MetricKind: "DELTA",
+				ValueType:  "DISTRIBUTION",
+				Points: []*monitoring.Point{{Interval: interval, Value: &monitoring.TypedValue{DistributionValue: &monitoring.Distribution{
+					Count:         180,
+					Mean:          2,
+					BucketOptions: &monitoring.BucketOptions{ExplicitBuckets: &monitoring.Explicit{Bounds: []float64{1, 5}}},
+					BucketCounts:  []int64{60, 60, 60},
+				}}}},
+			},
+			want: map[string]float64{
+				"stackdriver_gce_instance_custom_googleapis_com_latency_bucket{le=1}":    1,
+				"stackdriver_gce_instance_custom_googleapis_com_latency_bucket{le=5}":    2,
+				"stackdriver_gce_instance_custom_googleapis_com_latency_bucket{le=+Inf}": 3,
+				"stackdriver_gce_instance_custom_googleapis_com_latency_sum":             6,
+				"stackdriver_gce_instance_custom_googleapis_com_latency_count":           3,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := &summingCounterStore{}
+			// The prefix setting overrides AggregateDeltas.
+			c, err := NewMonitoringCollector("p", nil, MonitoringCollectorOptions{
+				AggregateDeltas: true,
+				PrefixConfigs:   []config.PrefixConfig{{Prefix: "custom.googleapis.com/", DeltaMode: config.DeltaModeRate}},
+			}, slog.Default(), store, nopHistogramStore{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			descriptor := &monitoring.MetricDescriptor{Type: test.timeSeries.Metric.Type, MetricKind: "DELTA"}
+			page := &monitoring.ListTimeSeriesResponse{TimeSeries: []*monitoring.TimeSeries{test.timeSeries}}
+			ch := make(chan prometheus.Metric, 10)
+			if err := c.reportTimeSeriesMetrics(page, descriptor, ch, time.Now(), &seriesDrops{}, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := gaugeValues(t, ch); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("exported %v, want %v", got, test.want)
+			}
+			if store.value != 0 {
+				t.Errorf("aggregated counter = %v, want rate series not to be aggregated", store.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+)
+
+// maxDeltaWindow bounds how far back an aggregated DELTA request reaches to
+// catch up on points missed while the exporter was not scraped.
+const maxDeltaWindow = 6 * time.Hour
+
+// deltaWindows remembers, per aggregated DELTA metric type, the end of the
+// last request window whose points were all added to the delta stores.
+type deltaWindows struct {
+	lock sync.Mutex
+	ends map[string]time.Time
+}
+
+func newDeltaWindows() *deltaWindows {
+	return &deltaWindows{ends: make(map[string]time.Time)}
+}
+
+// start returns the start of the request window ending at endTime. The window
+// spans at least one sample period and reaches back one sample period before
+// the end of the previous window, so points ingested late or ending on the
+// boundary are requested again; the delta stores add each point only once.
+func (w *deltaWindows) start(metricDescriptor *monitoring.MetricDescriptor, startTime, endTime time.Time) time.Time {
+	samplePeriod := descriptorSamplePeriod(metricDescriptor)
+	startTime = minTime(startTime, endTime.Add(-samplePeriod))
+
+	w.lock.Lock()
+	lastEnd, ok := w.ends[metricDescriptor.Type]
+	w.lock.Unlock()
+	if ok {
+		startTime = minTime(startTime, lastEnd.Add(-samplePeriod))
+	}
+	return maxTime(startTime, endTime.Add(-maxDeltaWindow))
+}
+
+// done records that every point of metricType up to endTime has been added.
+func (w *deltaWindows) done(metricType string, endTime time.Time) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.ends[metricType] = endTime
+}
+
+// descriptorSamplePeriod returns the sample period of a metric descriptor, or
+// 0 when its metadata does not have one.
+func descriptorSamplePeriod(metricDescriptor *monitoring.MetricDescriptor) time.Duration {
+	if metricDescriptor.Metadata == nil || metricDescriptor.Metadata.SamplePeriod == "" {
+		return 0
+	}
+	samplePeriod, err := time.ParseDuration(metricDescriptor.Metadata.SamplePeriod)
+	if err != nil {
+		return 0
+	}
+	return samplePeriod
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// collectDeltaPoints adds every point of an aggregated DELTA series to the
+// delta stores, oldest first. The stores track the end time of the last point
+// added to each series and ignore points that are not newer, so points seen by
+// a previous scrape are not counted twice.
+func (c *MonitoringCollector) collectDeltaPoints(timeSeriesMetrics *timeSeriesMetrics, timeSeries *monitoring.TimeSeries, layout *BucketLayout, labelKeys, labelValues []string) error {
+	type timedPoint struct {
+		startTime time.Time
+		endTime   time.Time
+		point     *monitoring.Point
+	}
+	points := make([]timedPoint, 0, len(timeSeries.Points))
+	for _, point := range timeSeries.Points {
+		endTime, err := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
+		if err != nil {
+			return fmt.Errorf("error parsing TimeSeries Point interval end time `%s`: %s", point.Interval.EndTime, err)
+		}
+		// The start time only seeds new series, so an unparseable one is left unknown.
+		startTime, _ := time.Parse(time.RFC3339Nano, point.Interval.StartTime)
+		points = append(points, timedPoint{startTime: startTime, endTime: endTime, point: point})
+	}
+	slices.SortFunc(points, func(a, b timedPoint) int {
+		return a.endTime.Compare(b.endTime)
+	})
+
+	for _, p := range points {
+		if timeSeries.ValueType == "DISTRIBUTION" {
+			dist := p.point.Value.DistributionValue
+			buckets, err := c.generateHistogramBuckets(dist, layout)
+			if err != nil {
+				c.logger.Debug("discarding", "resource", timeSeries.Resource.Type, "metric",
+					timeSeries.Metric.Type, "err", err)
+				return nil
+			}
+			timeSeriesMetrics.incrementDeltaHistogram(timeSeries, p.startTime, p.endTime, labelKeys, dist, buckets, labelValues)
+			continue
+		}
+
+		metricValue, ok := pointValue(timeSeries.ValueType, p.point)
+		if !ok {
+			c.logger.Debug("discarding", "value_type", timeSeries.ValueType, "metric", timeSeries)
+			return nil
+		}
+		timeSeriesMetrics.incrementDeltaConstMetric(timeSeries, p.startTime, p.endTime, labelKeys, prometheus.CounterValue, metricValue, labelValues)
+	}
+	return nil
+}
+
+// pointValue returns the value of a BOOL, INT64 or DOUBLE point.
+func pointValue(valueType string, point *monitoring.Point) (float64, bool) {
+	switch valueType {
+	case "BOOL":
+		if *point.Value.BoolValue {
+			return 1, true
+		}
+		return 0, true
+	case "INT64":
+		return float64(*point.Value.Int64Value), true
+	case "DOUBLE":
+		return *point.Value.DoubleValue, true
+	default:
+		return 0, false
+	}
+}
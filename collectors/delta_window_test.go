@@ -0,0 +1,136 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+)
+
+// summingCounterStore adds points that end after the last one added, like the
+// delta package stores, for a single series.
+type summingCounterStore struct {
+	value   float64
+	lastEnd time.Time
+}
+
+func (s *summingCounterStore) Increment(_ *monitoring.MetricDescriptor, v *ConstMetric) {
+	if !v.ReportTime.After(s.lastEnd) {
+		return
+	}
+	s.value += v.Value
+	s.lastEnd = v.ReportTime
+}
+
+func (s *summingCounterStore) ListMetrics(string) []*ConstMetric { return nil }
+
+func deltaTimeSeries(metricType string, endTimes []time.Time, values []int64) *monitoring.TimeSeries {
+	ts := &monitoring.TimeSeries{
+		Metric:     &monitoring.Metric{Type: metricType},
+		Resource:   &monitoring.MonitoredResource{Type: "gce_instance", Labels: map[string]string{"zone": "us-east1-b"}},
+		MetricKind: "DELTA",
+		ValueType:  "INT64",
+	}
+	for i := range endTimes {
+		ts.Points = append(ts.Points, &monitoring.Point{
+			Interval: &monitoring.TimeInterval{EndTime: endTimes[i].Format(time.RFC3339Nano)},
+			Value:    &monitoring.TypedValue{Int64Value: &values[i]},
+		})
+	}
+	return ts
+}
+
+func TestReportTimeSeriesMetricsAddsEveryDeltaPoint(t *testing.T) {
+	t.Parallel()
+
+	const metricType = "custom.googleapis.com/requests"
+	store := &summingCounterStore{}
+	c, err := NewMonitoringCollector("p", nil, MonitoringCollectorOptions{AggregateDeltas: true}, slog.Default(), store, nopHistogramStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptor := &monitoring.MetricDescriptor{Type: metricType, Unit: "1"}
+	base := time.Unix(1700000000, 0).UTC()
+	minute := func(i int) time.Time { return base.Add(time.Duration(i) * time.Minute) }
+
+	scrapes := []*monitoring.TimeSeries{
+		// Points are returned newest first.
+		deltaTimeSeries(metricType, []time.Time{minute(3), minute(2), minute(1)}, []int64{3, 2, 1}),
+		// The second window overlaps the first by one point.
+		deltaTimeSeries(metricType, []time.Time{minute(5), minute(4), minute(3)}, []int64{5, 4, 3}),
+	}
+	for _, ts := range scrapes {
+		page := &monitoring.ListTimeSeriesResponse{TimeSeries: []*monitoring.TimeSeries{ts}}
+		if err := c.reportTimeSeriesMetrics(page, descriptor, make(chan prometheus.Metric, 1), time.Now(), &seriesDrops{}, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if store.value != 15 {
+		t.Errorf("aggregated counter = %v, want 15", store.value)
+	}
+}
+
+func TestDeltaWindowsStart(t *testing.T) {
+	t.Parallel()
+
+	end := time.Unix(1700000000, 0)
+	descriptor := &monitoring.MetricDescriptor{
+		Type:     "custom.googleapis.com/requests",
+		Metadata: &monitoring.MetricDescriptorMetadata{SamplePeriod: "300s"},
+	}
+
+	tests := map[string]struct {
+		lastEnd time.Time
+		start   time.Time
+		want    time.Time
+	}{
+		"window shorter than the sample period": {
+			start: end.Add(-time.Minute),
+			want:  end.Add(-5 * time.Minute),
+		},
+		"window longer than the sample period": {
+			start: end.Add(-10 * time.Minute),
+			want:  end.Add(-10 * time.Minute),
+		},
+		"reaches back to the previous window": {
+			lastEnd: end.Add(-20 * time.Minute),
+			start:   end.Add(-10 * time.Minute),
+			want:    end.Add(-25 * time.Minute),
+		},
+		"bounded by maxDeltaWindow": {
+			lastEnd: end.Add(-24 * time.Hour),
+			start:   end.Add(-10 * time.Minute),
+			want:    end.Add(-maxDeltaWindow),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			w := newDeltaWindows()
+			if !test.lastEnd.IsZero() {
+				w.done(descriptor.Type, test.lastEnd)
+			}
+			if got := w.start(descriptor, test.start, end); !got.Equal(test.want) {
+				t.Errorf("start() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
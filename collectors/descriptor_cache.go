@@ -0,0 +1,259 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+)
+
+// projectMetricNamespaces hold metric types defined by each project, whose
+// descriptors differ between projects even though they are Google domains.
+var projectMetricNamespaces = []string{
+	"custom.googleapis.com/",
+	"external.googleapis.com/",
+	"workload.googleapis.com/",
+	"prometheus.googleapis.com/",
+	"logging.googleapis.com/user/",
+}
+
+// isGoogleOwnedPrefix reports whether every metric type starting with prefix
+// is defined by Google, so its descriptors are the same in every project.
+func isGoogleOwnedPrefix(prefix string) bool {
+	if !isGoogleMetric(prefix) {
+		return false
+	}
+	for _, ns := range projectMetricNamespaces {
+		if strings.HasPrefix(prefix, ns) || strings.HasPrefix(ns, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// descriptorListing identifies a listing of metric descriptors.
+type descriptorListing struct {
+	// project is the project the descriptors are listed from.
+	project string
+	prefix  string
+	// monitoredProject, if set, restricts the listing to descriptors with data in that project.
+	monitoredProject string
+}
+
+// filter returns the ListMetricDescriptors filter of the listing.
+func (l descriptorListing) filter() string {
+	if l.monitoredProject != "" {
+		return fmt.Sprintf("project = \"%s\" AND metric.type = starts_with(\"%s\")", l.monitoredProject, l.prefix)
+	}
+	return fmt.Sprintf("metric.type = starts_with(\"%s\")", l.prefix)
+}
+
+// cacheKey returns the key of the listing in the shared descriptor cache.
+// Descriptors defined by Google are the same in every project, so the project
+// they are listed from is left out and they are shared by all collectors.
+func (l descriptorListing) cacheKey() descriptorListing {
+	if isGoogleOwnedPrefix(l.prefix) {
+		l.project = ""
+	}
+	return l
+}
+
+// descriptorLister lists the descriptors of a listing, calling onCall for
+// each API call it makes.
+type descriptorLister func(ctx context.Context, listing descriptorListing, onCall func()) ([]*monitoring.MetricDescriptor, error)
+
+// sharedDescriptorCache caches the metric descriptors listed by every
+// collector of a Runtime. Entries older than the TTL are still served while a
+// single background refresh replaces them, so scrapes only wait for the first
+// listing of a key. Entries not looked up for twice the TTL are evicted.
+type sharedDescriptorCache struct {
+	ttl        time.Duration
+	timeout    time.Duration
+	onlyGoogle bool
+	lister     descriptorLister
+	logger     *slog.Logger
+
+	lock    sync.Mutex
+	entries map[descriptorListing]*sharedDescriptorEntry
+
+	hitsTotal      prometheus.Counter
+	missesTotal    prometheus.Counter
+	refreshesTotal *prometheus.CounterVec
+}
+
+type sharedDescriptorEntry struct {
+	// ready is closed once the first listing of the entry has completed.
+	ready chan struct{}
+	// listing is the listing refreshes repeat.
+	listing    descriptorListing
+	data       []*monitoring.MetricDescriptor
+	err        error
+	expiry     time.Time
+	lastUsed   time.Time
+	refreshing bool
+}
+
+// newSharedDescriptorCache returns a cache listing descriptors with list,
+// whose every listing is bounded by timeout.
+func newSharedDescriptorCache(logger *slog.Logger, ttl, timeout time.Duration, onlyGoogle bool, list descriptorLister) *sharedDescriptorCache {
+	return &sharedDescriptorCache{
+		ttl:        ttl,
+		timeout:    timeout,
+		onlyGoogle: onlyGoogle,
+		lister:     list,
+		logger:     logger,
+		entries:    make(map[descriptorListing]*sharedDescriptorEntry),
+		hitsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "stackdriver_exporter",
+			Name:      "descriptor_cache_hits_total",
+			Help:      "Total number of metric descriptor listings served from the descriptor cache.",
+		}),
+		missesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "stackdriver_exporter",
+			Name:      "descriptor_cache_misses_total",
+			Help:      "Total number of metric descriptor listings not found in the descriptor cache, including those waiting for another collector's listing.",
+		}),
+		refreshesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "stackdriver_exporter",
+			Name:      "descriptor_cache_refreshes_total",
+			Help:      "Total number of background refreshes of expired descriptor cache entries.",
+		}, []string{"result"}),
+	}
+}
+
+// cacheable reports whether the descriptors of prefix are cached.
+func (d *sharedDescriptorCache) cacheable(prefix string) bool {
+	return d != nil && d.ttl > 0 && (!d.onlyGoogle || isGoogleMetric(prefix))
+}
+
+// get returns the descriptors cached for a listing, listing them if there are
+// none. Concurrent callers wait for the same listing, whose API calls are
+// reported to onCall of the caller making them. An expired entry is returned
+// as is and refreshed in the background.
+func (d *sharedDescriptorCache) get(listing descriptorListing, onCall func()) ([]*monitoring.MetricDescriptor, error) {
+	key := listing.cacheKey()
+	now := time.Now()
+	d.lock.Lock()
+	entry, ok := d.entries[key]
+	if !ok {
+		d.evictIdle(now)
+		entry = &sharedDescriptorEntry{ready: make(chan struct{}), listing: listing, lastUsed: now}
+		d.entries[key] = entry
+		d.lock.Unlock()
+		d.missesTotal.Inc()
+
+		data, err := d.list(listing, onCall)
+		d.lock.Lock()
+		entry.data, entry.err, entry.expiry = data, err, time.Now().Add(d.ttl)
+		if err != nil {
+			// The next caller lists the descriptors again.
+			delete(d.entries, key)
+		}
+		d.lock.Unlock()
+		close(entry.ready)
+		return data, err
+	}
+	entry.lastUsed = now
+	d.lock.Unlock()
+
+	select {
+	case <-entry.ready:
+		d.hitsTotal.Inc()
+	default:
+		// The descriptors are being listed for another caller.
+		d.missesTotal.Inc()
+		<-entry.ready
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	if time.Now().After(entry.expiry) && !entry.refreshing {
+		entry.refreshing = true
+		go d.refresh(entry)
+	}
+	return entry.data, nil
+}
+
+// list lists the descriptors of a listing, detached from the scrape that
+// needs them since other scrapes may wait for the same listing.
+func (d *sharedDescriptorCache) list(listing descriptorListing, onCall func()) ([]*monitoring.MetricDescriptor, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+	return d.lister(ctx, listing, onCall)
+}
+
+// evictIdle removes the listed entries not looked up for twice the TTL. It
+// must be called with the lock held.
+func (d *sharedDescriptorCache) evictIdle(now time.Time) {
+	for key, entry := range d.entries {
+		select {
+		case <-entry.ready:
+		default:
+			continue
+		}
+		if !entry.refreshing && now.Sub(entry.lastUsed) > 2*d.ttl {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// forgetProject removes the entries listed from, or restricted to, a
+// project. Entries shared with other projects are listed again by the next
+// collector needing them.
+func (d *sharedDescriptorCache) forgetProject(projectID string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for key, entry := range d.entries {
+		if entry.listing.project == projectID || entry.listing.monitoredProject == projectID {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// refresh lists the descriptors of an expired entry again. On error the stale
+// descriptors are kept and the next lookup tries again.
+func (d *sharedDescriptorCache) refresh(entry *sharedDescriptorEntry) {
+	data, err := d.list(entry.listing, func() {})
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	entry.refreshing = false
+	if err != nil {
+		d.logger.Warn("error refreshing cached metric descriptors, keeping the stale ones", "project", entry.listing.project, "prefix", entry.listing.prefix, "err", err)
+		d.refreshesTotal.WithLabelValues("error").Inc()
+		return
+	}
+	entry.data, entry.expiry = data, time.Now().Add(d.ttl)
+	d.refreshesTotal.WithLabelValues("success").Inc()
+}
+
+func (d *sharedDescriptorCache) Describe(ch chan<- *prometheus.Desc) {
+	d.hitsTotal.Describe(ch)
+	d.missesTotal.Describe(ch)
+	d.refreshesTotal.Describe(ch)
+}
+
+func (d *sharedDescriptorCache) Collect(ch chan<- prometheus.Metric) {
+	d.hitsTotal.Collect(ch)
+	d.missesTotal.Collect(ch)
+	d.refreshesTotal.Collect(ch)
+}
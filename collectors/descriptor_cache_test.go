@@ -0,0 +1,267 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/api/monitoring/v3"
+)
+
+// fakeDescriptorLister lists the descriptors returned by next, once per call.
+type fakeDescriptorLister struct {
+	lock      sync.Mutex
+	next      func(listing descriptorListing) ([]*monitoring.MetricDescriptor, error)
+	listed    []descriptorListing
+	deadlines []bool
+	release   chan struct{}
+}
+
+func (f *fakeDescriptorLister) list(ctx context.Context, listing descriptorListing, onCall func()) ([]*monitoring.MetricDescriptor, error) {
+	_, hasDeadline := ctx.Deadline()
+	f.lock.Lock()
+	release, next := f.release, f.next
+	f.listed = append(f.listed, listing)
+	f.deadlines = append(f.deadlines, hasDeadline)
+	f.lock.Unlock()
+	if release != nil {
+		<-release
+	}
+	onCall()
+	return next(listing)
+}
+
+func (f *fakeDescriptorLister) returns(descriptors []*monitoring.MetricDescriptor, err error, release chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.next = func(descriptorListing) ([]*monitoring.MetricDescriptor, error) { return descriptors, err }
+	f.release = release
+}
+
+func (f *fakeDescriptorLister) calls() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.listed)
+}
+
+func TestSharedDescriptorCacheServesStaleEntries(t *testing.T) {
+	t.Parallel()
+
+	ttl := 10 * time.Millisecond
+	lister := &fakeDescriptorLister{}
+	cache := newSharedDescriptorCache(slog.Default(), ttl, time.Minute, false, lister.list)
+	listing := descriptorListing{project: "p", prefix: "custom.googleapis.com/"}
+	old, fresh := makeDummyMetrics(1), makeDummyMetrics(2)
+	var callerCalls atomic.Int32
+	onCall := func() { callerCalls.Add(1) }
+
+	lister.returns(old, nil, nil)
+	if got, err := cache.get(listing, onCall); err != nil || !isEqual(got, old) {
+		t.Fatalf("get() on miss = %v, %v", got, err)
+	}
+	lister.returns(fresh, nil, nil)
+	if got, _ := cache.get(listing, onCall); !isEqual(got, old) {
+		t.Fatalf("get() before expiry = %v, want the cached descriptors", got)
+	}
+
+	time.Sleep(ttl)
+	release := make(chan struct{})
+	lister.returns(fresh, nil, release)
+	if got, _ := cache.get(listing, onCall); !isEqual(got, old) {
+		t.Fatalf("get() after expiry = %v, want the stale descriptors while refreshing", got)
+	}
+	close(release)
+	for testutil.ToFloat64(cache.refreshesTotal.WithLabelValues("success")) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if got, _ := cache.get(listing, onCall); !isEqual(got, fresh) {
+		t.Fatalf("get() after refresh = %v, want the refreshed descriptors", got)
+	}
+
+	if hits, misses := testutil.ToFloat64(cache.hitsTotal), testutil.ToFloat64(cache.missesTotal); hits != 3 || misses != 1 {
+		t.Errorf("hits, misses = %v, %v, want 3, 1", hits, misses)
+	}
+	// The background refresh is not reported to the collector that found the entry expired.
+	if got := callerCalls.Load(); got != 1 {
+		t.Errorf("API calls reported to the caller = %d, want 1", got)
+	}
+	if lister.calls() != 2 || lister.listed[1] != listing {
+		t.Errorf("listings = %v, want the entry's listing refreshed", lister.listed)
+	}
+	for i, hasDeadline := range lister.deadlines {
+		if !hasDeadline {
+			t.Errorf("listing %d has no deadline, want it bounded by the timeout", i)
+		}
+	}
+}
+
+func TestSharedDescriptorCacheListsOnceForConcurrentMisses(t *testing.T) {
+	t.Parallel()
+
+	lister := &fakeDescriptorLister{}
+	release := make(chan struct{})
+	lister.returns(makeDummyMetrics(3), nil, release)
+	cache := newSharedDescriptorCache(slog.Default(), time.Hour, time.Minute, false, lister.list)
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Descriptors defined by Google are shared whichever project lists them.
+			listing := descriptorListing{project: fmt.Sprintf("p%d", i), prefix: "compute.googleapis.com/"}
+			if got, err := cache.get(listing, func() {}); err != nil || len(got) != 3 {
+				t.Errorf("get() = %v, %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := lister.calls(); got != 1 {
+		t.Errorf("descriptors listed %d times, want 1", got)
+	}
+	// Callers waiting for the listing of another caller did not find descriptors in the cache.
+	if hits, misses := testutil.ToFloat64(cache.hitsTotal), testutil.ToFloat64(cache.missesTotal); hits != 0 || misses != 5 {
+		t.Errorf("hits, misses = %v, %v, want 0, 5", hits, misses)
+	}
+}
+
+func TestSharedDescriptorCacheDoesNotCacheErrors(t *testing.T) {
+	t.Parallel()
+
+	lister := &fakeDescriptorLister{}
+	cache := newSharedDescriptorCache(slog.Default(), time.Hour, time.Minute, false, lister.list)
+	listing := descriptorListing{project: "p", prefix: "compute.googleapis.com/"}
+
+	lister.returns(nil, errors.New("unavailable"), nil)
+	if _, err := cache.get(listing, func() {}); err == nil {
+		t.Fatal("get() err = nil, want the listing error")
+	}
+	lister.returns(makeDummyMetrics(1), nil, nil)
+	if got, err := cache.get(listing, func() {}); err != nil || len(got) != 1 {
+		t.Fatalf("get() after error = %v, %v, want the descriptors listed again", got, err)
+	}
+}
+
+func TestSharedDescriptorCacheEvictsIdleEntries(t *testing.T) {
+	t.Parallel()
+
+	ttl := 10 * time.Millisecond
+	lister := &fakeDescriptorLister{}
+	lister.returns(makeDummyMetrics(1), nil, nil)
+	cache := newSharedDescriptorCache(slog.Default(), ttl, time.Minute, false, lister.list)
+	idle := descriptorListing{project: "p", prefix: "custom.googleapis.com/idle"}
+	used := descriptorListing{project: "p", prefix: "custom.googleapis.com/used"}
+
+	for _, listing := range []descriptorListing{idle, used} {
+		if _, err := cache.get(listing, func() {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 3 {
+		time.Sleep(ttl)
+		if _, err := cache.get(used, func() {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cache.get(descriptorListing{project: "p", prefix: "custom.googleapis.com/new"}, func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if _, ok := cache.entries[idle.cacheKey()]; ok {
+		t.Error("entry not looked up for twice the TTL was kept")
+	}
+	if _, ok := cache.entries[used.cacheKey()]; !ok {
+		t.Error("entry looked up within the TTL was evicted")
+	}
+}
+
+func TestDescriptorListingCacheKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b  descriptorListing
+		share bool
+	}{
+		{
+			a:     descriptorListing{project: "p", prefix: "compute.googleapis.com/"},
+			b:     descriptorListing{project: "q", prefix: "compute.googleapis.com/"},
+			share: true,
+		},
+		{
+			a:     descriptorListing{project: "p", prefix: "custom.googleapis.com/"},
+			b:     descriptorListing{project: "q", prefix: "custom.googleapis.com/"},
+			share: false,
+		},
+		{
+			a:     descriptorListing{project: "p", prefix: "compute.googleapis.com/", monitoredProject: "p"},
+			b:     descriptorListing{project: "q", prefix: "compute.googleapis.com/", monitoredProject: "q"},
+			share: false,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.a.cacheKey() == tt.b.cacheKey(); got != tt.share {
+			t.Errorf("%+v and %+v share a cache entry = %v, want %v", tt.a, tt.b, got, tt.share)
+		}
+	}
+}
+
+func TestIsGoogleOwnedPrefix(t *testing.T) {
+	t.Parallel()
+
+	for prefix, want := range map[string]bool{
+		"compute.googleapis.com/instance":        true,
+		"logging.googleapis.com/byte_count":      true,
+		"logging.googleapis.com/":                false,
+		"logging.googleapis.com/user/errors":     false,
+		"custom.googleapis.com/requests":         false,
+		"prometheus.googleapis.com/up/gauge":     false,
+		"kubernetes.io/container/cpu/core_usage": false,
+	} {
+		if got := isGoogleOwnedPrefix(prefix); got != want {
+			t.Errorf("isGoogleOwnedPrefix(%q) = %v, want %v", prefix, got, want)
+		}
+	}
+}
+
+func TestRuntimeCollectorsUseSharedDescriptorCache(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRuntime(func(context.Context) ([]string, error) { return []string{"p"}, nil })
+	r.cfg.DescriptorCacheTTL = time.Hour
+	r.descriptorCache = newSharedDescriptorCache(slog.Default(), time.Hour, time.Minute, false, (&fakeDescriptorLister{}).list)
+
+	c, err := r.newCollector("p", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.sharedDescriptors != r.descriptorCache {
+		t.Error("collector does not use the descriptor cache of the Runtime")
+	}
+	if _, ok := c.descriptorCache.(*noopDescriptorCache); !ok {
+		t.Errorf("collector built a descriptor cache of its own, %T", c.descriptorCache)
+	}
+}
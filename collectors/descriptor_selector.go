@@ -0,0 +1,98 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+// DescriptorSelector decides which metric descriptors returned for the
+// configured prefixes are scraped. The zero value selects every descriptor.
+// Include lists are ignored when empty; matching of launch stages, metric
+// kinds and value types is case-insensitive.
+type DescriptorSelector struct {
+	// ExcludePrefixes drops descriptors whose type starts with any of the prefixes.
+	ExcludePrefixes []string
+	// ExcludeRegexes drops descriptors whose type matches any of the expressions.
+	ExcludeRegexes []*regexp.Regexp
+	// IncludeLaunchStages, if set, keeps only descriptors with one of the launch stages.
+	// A missing launch stage is treated as LAUNCH_STAGE_UNSPECIFIED.
+	IncludeLaunchStages []string
+	// ExcludeLaunchStages drops descriptors with one of the launch stages.
+	ExcludeLaunchStages []string
+	// IncludeMetricKinds, if set, keeps only descriptors of one of the metric kinds.
+	IncludeMetricKinds []string
+	// ExcludeMetricKinds drops descriptors of one of the metric kinds.
+	ExcludeMetricKinds []string
+	// IncludeValueTypes, if set, keeps only descriptors of one of the value types.
+	IncludeValueTypes []string
+	// ExcludeValueTypes drops descriptors of one of the value types.
+	ExcludeValueTypes []string
+}
+
+// Matches reports whether the descriptor should be scraped.
+func (s *DescriptorSelector) Matches(descriptor *monitoring.MetricDescriptor) bool {
+	for _, prefix := range s.ExcludePrefixes {
+		if strings.HasPrefix(descriptor.Type, prefix) {
+			return false
+		}
+	}
+	for _, re := range s.ExcludeRegexes {
+		if re.MatchString(descriptor.Type) {
+			return false
+		}
+	}
+
+	launchStage := descriptor.LaunchStage
+	if launchStage == "" {
+		launchStage = "LAUNCH_STAGE_UNSPECIFIED"
+	}
+	return selectedValue(launchStage, s.IncludeLaunchStages, s.ExcludeLaunchStages) &&
+		selectedValue(descriptor.MetricKind, s.IncludeMetricKinds, s.ExcludeMetricKinds) &&
+		selectedValue(descriptor.ValueType, s.IncludeValueTypes, s.ExcludeValueTypes)
+}
+
+// Filter returns the descriptors that should be scraped. The input slice is
+// not modified.
+func (s *DescriptorSelector) Filter(descriptors []*monitoring.MetricDescriptor) []*monitoring.MetricDescriptor {
+	if s.isZero() {
+		return descriptors
+	}
+	out := make([]*monitoring.MetricDescriptor, 0, len(descriptors))
+	for _, descriptor := range descriptors {
+		if s.Matches(descriptor) {
+			out = append(out, descriptor)
+		}
+	}
+	return out
+}
+
+func (s *DescriptorSelector) isZero() bool {
+	return len(s.ExcludePrefixes) == 0 && len(s.ExcludeRegexes) == 0 &&
+		len(s.IncludeLaunchStages) == 0 && len(s.ExcludeLaunchStages) == 0 &&
+		len(s.IncludeMetricKinds) == 0 && len(s.ExcludeMetricKinds) == 0 &&
+		len(s.IncludeValueTypes) == 0 && len(s.ExcludeValueTypes) == 0
+}
+
+func selectedValue(value string, include, exclude []string) bool {
+	equalFold := func(v string) bool { return strings.EqualFold(v, value) }
+	if len(include) > 0 && !slices.ContainsFunc(include, equalFold) {
+		return false
+	}
+	return !slices.ContainsFunc(exclude, equalFold)
+}
@@ -0,0 +1,115 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"regexp"
+	"testing"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+func TestDescriptorSelectorMatches(t *testing.T) {
+	t.Parallel()
+
+	gaDelta := &monitoring.MetricDescriptor{
+		Type:        "compute.googleapis.com/instance/network/received_bytes_count",
+		LaunchStage: "GA",
+		MetricKind:  "DELTA",
+		ValueType:   "INT64",
+	}
+	deprecatedGauge := &monitoring.MetricDescriptor{
+		Type:        "compute.googleapis.com/instance/uptime",
+		LaunchStage: "DEPRECATED",
+		MetricKind:  "GAUGE",
+		ValueType:   "DOUBLE",
+	}
+	unspecified := &monitoring.MetricDescriptor{
+		Type:       "custom.googleapis.com/queue/depth",
+		MetricKind: "GAUGE",
+		ValueType:  "DISTRIBUTION",
+	}
+
+	tests := []struct {
+		name     string
+		selector DescriptorSelector
+		want     []bool
+	}{
+		{
+			name:     "zero value selects everything",
+			selector: DescriptorSelector{},
+			want:     []bool{true, true, true},
+		},
+		{
+			name:     "exclude prefix",
+			selector: DescriptorSelector{ExcludePrefixes: []string{"compute.googleapis.com/instance/network"}},
+			want:     []bool{false, true, true},
+		},
+		{
+			name:     "exclude regex",
+			selector: DescriptorSelector{ExcludeRegexes: []*regexp.Regexp{regexp.MustCompile(`^(?:.*/uptime)$`)}},
+			want:     []bool{true, false, true},
+		},
+		{
+			name:     "exclude launch stage",
+			selector: DescriptorSelector{ExcludeLaunchStages: []string{"deprecated"}},
+			want:     []bool{true, false, true},
+		},
+		{
+			name:     "include launch stage treats missing as unspecified",
+			selector: DescriptorSelector{IncludeLaunchStages: []string{"GA", "LAUNCH_STAGE_UNSPECIFIED"}},
+			want:     []bool{true, false, true},
+		},
+		{
+			name:     "include metric kind",
+			selector: DescriptorSelector{IncludeMetricKinds: []string{"GAUGE"}},
+			want:     []bool{false, true, true},
+		},
+		{
+			name:     "exclude value type",
+			selector: DescriptorSelector{ExcludeValueTypes: []string{"DISTRIBUTION"}},
+			want:     []bool{true, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			for i, descriptor := range []*monitoring.MetricDescriptor{gaDelta, deprecatedGauge, unspecified} {
+				if got := tt.selector.Matches(descriptor); got != tt.want[i] {
+					t.Errorf("Matches(%s) = %v, want %v", descriptor.Type, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDescriptorSelectorFilter(t *testing.T) {
+	t.Parallel()
+
+	descriptors := []*monitoring.MetricDescriptor{
+		{Type: "compute.googleapis.com/instance/uptime", MetricKind: "GAUGE"},
+		{Type: "compute.googleapis.com/instance/cpu/usage_time", MetricKind: "DELTA"},
+	}
+	selector := DescriptorSelector{ExcludeMetricKinds: []string{"DELTA"}}
+
+	got := selector.Filter(descriptors)
+	if len(got) != 1 || got[0].Type != "compute.googleapis.com/instance/uptime" {
+		t.Fatalf("Filter() = %v, want only the GAUGE descriptor", got)
+	}
+	if len(descriptors) != 2 || descriptors[1].MetricKind != "DELTA" {
+		t.Fatalf("Filter() mutated input = %v", descriptors)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+// collectGaugePolicies exports one value per policy, computed from every
+// point of a GAUGE series, at the time of its newest point.
+func (c *MonitoringCollector) collectGaugePolicies(tsm *timeSeriesMetrics, timeSeries *monitoring.TimeSeries, policies []config.GaugePolicyConfig, labelKeys, labelValues []string) error {
+	var values pointValues
+	for _, point := range timeSeries.Points {
+		endTime, err := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
+		if err != nil {
+			return fmt.Errorf("error parsing TimeSeries Point interval end time `%s`: %s", point.Interval.EndTime, err)
+		}
+		value, ok := pointValue(timeSeries.ValueType, point)
+		if !ok {
+			return fmt.Errorf("unsupported value type %s", timeSeries.ValueType)
+		}
+		values.add(endTime, value)
+	}
+	if values.count == 0 {
+		return nil
+	}
+
+	fqName := buildFQName(timeSeries)
+	for _, policy := range policies {
+		tsm.collectConstMetric(fqName+policy.Suffix, values.newestTime, labelKeys, prometheus.GaugeValue, values.get(policy.Policy), labelValues)
+	}
+	return nil
+}
+
+// pointValues summarizes the values of the points of a series.
+type pointValues struct {
+	newestTime time.Time
+	newest     float64
+	max        float64
+	min        float64
+	sum        float64
+	count      int
+}
+
+func (v *pointValues) add(endTime time.Time, value float64) {
+	if v.count == 0 {
+		v.max, v.min = value, value
+	}
+	if v.count == 0 || endTime.After(v.newestTime) {
+		v.newestTime, v.newest = endTime, value
+	}
+	v.max = math.Max(v.max, value)
+	v.min = math.Min(v.min, value)
+	v.sum += value
+	v.count++
+}
+
+func (v *pointValues) get(policy string) float64 {
+	switch policy {
+	case config.GaugePolicyMax:
+		return v.max
+	case config.GaugePolicyMin:
+		return v.min
+	case config.GaugePolicyMean:
+		return v.sum / float64(v.count)
+	case config.GaugePolicySum:
+		return v.sum
+	case config.GaugePolicyCount:
+		return float64(v.count)
+	default:
+		return v.newest
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+func TestReportTimeSeriesMetricsGaugePolicies(t *testing.T) {
+	t.Parallel()
+
+	const metricType = "custom.googleapis.com/queue_depth"
+	c, err := NewMonitoringCollector("p", nil, MonitoringCollectorOptions{
+		PrefixConfigs: []config.PrefixConfig{{
+			Prefix: "custom.googleapis.com/",
+			GaugePolicies: []config.GaugePolicyConfig{
+				{Policy: config.GaugePolicyNewest},
+				{Policy: config.GaugePolicyMax, Suffix: "_max"},
+				{Policy: config.GaugePolicyMin, Suffix: "_min"},
+				{Policy: config.GaugePolicyMean, Suffix: "_mean"},
+				{Policy: config.GaugePolicySum, Suffix: "_sum"},
+				{Policy: config.GaugePolicyCount, Suffix: "_count"},
+			},
+		}},
+	}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Unix(1700000000, 0).UTC()
+	ts := deltaTimeSeries(metricType, []time.Time{base.Add(2 * time.Minute), base.Add(time.Minute), base}, []int64{3, 5, 1})
+	ts.MetricKind = "GAUGE"
+	page := &monitoring.ListTimeSeriesResponse{TimeSeries: []*monitoring.TimeSeries{ts}}
+	ch := make(chan prometheus.Metric, 10)
+	if err := c.reportTimeSeriesMetrics(page, &monitoring.MetricDescriptor{Type: metricType}, ch, time.Now(), &seriesDrops{}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	const fqName = "stackdriver_gce_instance_custom_googleapis_com_queue_depth"
+	want := map[string]float64{
+		fqName:            3,
+		fqName + "_max":   5,
+		fqName + "_min":   1,
+		fqName + "_mean":  3,
+		fqName + "_sum":   9,
+		fqName + "_count": 3,
+	}
+	if got := gaugeValues(t, ch); !reflect.DeepEqual(got, want) {
+		t.Errorf("exported %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	cloudresourcemanagerv3 "google.golang.org/api/cloudresourcemanager/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+// hierarchyLister lists the direct children of a folder or organization.
+type hierarchyLister interface {
+	projects(ctx context.Context, parent string) ([]*cloudresourcemanagerv3.Project, error)
+	folders(ctx context.Context, parent string) ([]string, error)
+}
+
+type resourceManagerLister struct {
+	service *cloudresourcemanagerv3.Service
+}
+
+func (l *resourceManagerLister) projects(ctx context.Context, parent string) ([]*cloudresourcemanagerv3.Project, error) {
+	var projects []*cloudresourcemanagerv3.Project
+	err := l.service.Projects.Search().Query("parent:"+parent).Pages(ctx, func(page *cloudresourcemanagerv3.SearchProjectsResponse) error {
+		projects = append(projects, page.Projects...)
+		return nil
+	})
+	return projects, err
+}
+
+func (l *resourceManagerLister) folders(ctx context.Context, parent string) ([]string, error) {
+	var folders []string
+	err := l.service.Folders.List().Parent(parent).Pages(ctx, func(page *cloudresourcemanagerv3.ListFoldersResponse) error {
+		for _, folder := range page.Folders {
+			folders = append(folders, folder.Name)
+		}
+		return nil
+	})
+	return folders, err
+}
+
+// projectExclusion drops projects found below the configured parents.
+type projectExclusion struct {
+	labels  []string
+	regexes []*regexp.Regexp
+	states  []string
+}
+
+func newProjectExclusion(cfg *config.Config) *projectExclusion {
+	e := &projectExclusion{
+		labels: cfg.ProjectsExcludeLabels,
+		states: cfg.ProjectsExcludeStates,
+	}
+	for _, re := range cfg.ProjectsExcludeRegexes {
+		e.regexes = append(e.regexes, regexp.MustCompile("^(?:"+re+")$"))
+	}
+	return e
+}
+
+// excludes reports whether the project carries one of the excluded labels,
+// given as key or key=value, has an excluded lifecycle state or an ID
+// matching one of the excluded expressions.
+func (e *projectExclusion) excludes(project *cloudresourcemanagerv3.Project) bool {
+	for _, label := range e.labels {
+		key, value, hasValue := strings.Cut(label, "=")
+		if v, ok := project.Labels[key]; ok && (!hasValue || v == value) {
+			return true
+		}
+	}
+	if slices.ContainsFunc(e.states, func(state string) bool { return strings.EqualFold(state, project.State) }) {
+		return true
+	}
+	return slices.ContainsFunc(e.regexes, func(re *regexp.Regexp) bool { return re.MatchString(project.ProjectId) })
+}
+
+// walkProjectHierarchy returns the IDs of the projects below parents, walking
+// their folders recursively, that are not excluded.
+func walkProjectHierarchy(ctx context.Context, lister hierarchyLister, parents []string, exclusion *projectExclusion) ([]string, error) {
+	var projectIDs []string
+	visited := make(map[string]struct{})
+	queue := slices.Clone(parents)
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[parent]; ok {
+			continue
+		}
+		visited[parent] = struct{}{}
+
+		projects, err := lister.projects(ctx, parent)
+		if err != nil {
+			return nil, fmt.Errorf("listing projects of %s: %w", parent, err)
+		}
+		for _, project := range projects {
+			if !exclusion.excludes(project) {
+				projectIDs = append(projectIDs, project.ProjectId)
+			}
+		}
+
+		folders, err := lister.folders(ctx, parent)
+		if err != nil {
+			return nil, fmt.Errorf("listing folders of %s: %w", parent, err)
+		}
+		queue = append(queue, folders...)
+	}
+	return projectIDs, nil
+}
+
+// getProjectIDsFromParents returns the IDs of the projects below the
+// configured folders and organizations.
+func getProjectIDsFromParents(ctx context.Context, cfg *config.Config) ([]string, error) {
+	service, err := cloudresourcemanagerv3.NewService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return walkProjectHierarchy(ctx, &resourceManagerLister{service: service}, cfg.ProjectsParents, newProjectExclusion(cfg))
+}
@@ -0,0 +1,106 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	cloudresourcemanagerv3 "google.golang.org/api/cloudresourcemanager/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+type fakeHierarchy struct {
+	subfolders       map[string][]string
+	projectsByParent map[string][]*cloudresourcemanagerv3.Project
+	fail             string
+}
+
+func (f *fakeHierarchy) projects(_ context.Context, parent string) ([]*cloudresourcemanagerv3.Project, error) {
+	if parent == f.fail {
+		return nil, errors.New("permission denied")
+	}
+	return f.projectsByParent[parent], nil
+}
+
+func (f *fakeHierarchy) folders(_ context.Context, parent string) ([]string, error) {
+	return f.subfolders[parent], nil
+}
+
+func activeProject(id string, labels map[string]string) *cloudresourcemanagerv3.Project {
+	return &cloudresourcemanagerv3.Project{ProjectId: id, State: "ACTIVE", Labels: labels}
+}
+
+func TestWalkProjectHierarchy(t *testing.T) {
+	t.Parallel()
+
+	hierarchy := &fakeHierarchy{
+		subfolders: map[string][]string{
+			"organizations/1": {"folders/10", "folders/11"},
+			"folders/10":      {"folders/100"},
+		},
+		projectsByParent: map[string][]*cloudresourcemanagerv3.Project{
+			"organizations/1": {activeProject("org-project", nil)},
+			"folders/10":      {activeProject("team-a", nil), activeProject("sandbox-a", nil)},
+			"folders/11":      {activeProject("team-b", map[string]string{"monitoring": "off"})},
+			"folders/100": {
+				activeProject("team-a-nested", map[string]string{"env": "prod"}),
+				{ProjectId: "team-a-deleted", State: "DELETE_REQUESTED"},
+			},
+		},
+	}
+	cfg := &config.Config{
+		ProjectsExcludeLabels:  []string{"monitoring=off"},
+		ProjectsExcludeRegexes: []string{"sandbox-.*"},
+		ProjectsExcludeStates:  config.DefaultProjectsExcludeStates,
+	}
+
+	got, err := walkProjectHierarchy(context.Background(), hierarchy, []string{"organizations/1", "folders/10"}, newProjectExclusion(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	want := []string{"org-project", "team-a", "team-a-nested"}
+	if !slices.Equal(got, want) {
+		t.Errorf("walkProjectHierarchy() = %v, want %v", got, want)
+	}
+
+	hierarchy.fail = "folders/100"
+	if _, err := walkProjectHierarchy(context.Background(), hierarchy, []string{"organizations/1"}, newProjectExclusion(cfg)); err == nil {
+		t.Error("expected error listing folders/100, got nil")
+	}
+}
+
+func TestProjectExclusionLabels(t *testing.T) {
+	t.Parallel()
+
+	e := newProjectExclusion(&config.Config{ProjectsExcludeLabels: []string{"ephemeral", "env=dev"}})
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{labels: nil, want: false},
+		{labels: map[string]string{"ephemeral": ""}, want: true},
+		{labels: map[string]string{"env": "dev"}, want: true},
+		{labels: map[string]string{"env": "prod"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := e.excludes(activeProject("p", tt.labels)); got != tt.want {
+			t.Errorf("excludes(labels=%v) = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// Bounds returns the sorted upper bounds of the buckets of h.
+func (h *HistogramMetric) Bounds() []float64 {
+	return slices.Sorted(maps.Keys(h.Buckets))
+}
+
+// SameLayout reports whether h and other have the same bucket bounds.
+func (h *HistogramMetric) SameLayout(other *HistogramMetric) bool {
+	if len(h.Buckets) != len(other.Buckets) {
+		return false
+	}
+	for bound := range h.Buckets {
+		if _, ok := other.Buckets[bound]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Rebucket moves the cumulative bucket counts of h onto bounds. The count at
+// a bound that is not one of the current bounds is interpolated linearly
+// within the current bucket holding it, assuming the first bucket starts at
+// 0, and rounded down, so the buckets stay cumulative. Count and Sum are
+// unchanged.
+func (h *HistogramMetric) Rebucket(bounds []float64) {
+	h.Buckets = rebucket(h.Buckets, bounds)
+}
+
+func rebucket(buckets map[float64]uint64, bounds []float64) map[float64]uint64 {
+	current := slices.Sorted(maps.Keys(buckets))
+	out := make(map[float64]uint64, len(bounds))
+	for _, bound := range bounds {
+		out[bound] = cumulativeCountAt(buckets, current, bound)
+	}
+	return out
+}
+
+// BucketLayout is the bucket layout distributions are re-bucketed onto:
+// Bounds, or at most MaxBuckets buckets including the +Inf one.
+type BucketLayout struct {
+	Bounds     []float64
+	MaxBuckets int
+}
+
+// apply re-buckets cumulative buckets onto the layout. Bounds that are not
+// bounds of buckets are interpolated, while MaxBuckets keeps evenly spaced
+// bounds of buckets, so their counts are exact.
+func (l *BucketLayout) apply(buckets map[float64]uint64) map[float64]uint64 {
+	if l == nil {
+		return buckets
+	}
+	if len(l.Bounds) > 0 {
+		bounds := slices.Clone(l.Bounds)
+		if !math.IsInf(bounds[len(bounds)-1], 1) {
+			bounds = append(bounds, math.Inf(1))
+		}
+		return rebucket(buckets, bounds)
+	}
+	if l.MaxBuckets == 0 || len(buckets) <= l.MaxBuckets {
+		return buckets
+	}
+	current := slices.Sorted(maps.Keys(buckets))
+	finite := current[:len(current)-1]
+	step := (len(finite) + l.MaxBuckets - 2) / (l.MaxBuckets - 1)
+	bounds := make([]float64, 0, l.MaxBuckets)
+	for i := step - 1; i < len(finite); i += step {
+		bounds = append(bounds, finite[i])
+	}
+	return rebucket(buckets, append(bounds, current[len(current)-1]))
+}
+
+// cumulativeCountAt estimates the number of observations less than or equal
+// to bound from cumulative buckets whose sorted bounds are given.
+func cumulativeCountAt(buckets map[float64]uint64, bounds []float64, bound float64) uint64 {
+	if len(bounds) == 0 {
+		return 0
+	}
+	if count, ok := buckets[bound]; ok {
+		return count
+	}
+	i, _ := slices.BinarySearch(bounds, bound)
+	switch {
+	case i == len(bounds):
+		// Above the highest bound, every observation has been counted.
+		return buckets[bounds[len(bounds)-1]]
+	case i == 0:
+		upper := bounds[0]
+		if bound <= 0 || upper <= 0 || math.IsInf(upper, 1) {
+			return 0
+		}
+		return uint64(float64(buckets[upper]) * bound / upper)
+	}
+	lower, upper := bounds[i-1], bounds[i]
+	if math.IsInf(upper, 1) {
+		return buckets[lower]
+	}
+	lowerCount, upperCount := buckets[lower], buckets[upper]
+	if upperCount < lowerCount {
+		return lowerCount
+	}
+	return lowerCount + uint64(float64(upperCount-lowerCount)*(bound-lower)/(upper-lower))
+}
+
+// Quantiles estimates quantiles from the buckets of h by linear interpolation
+// within the bucket holding each of them, like histogram_quantile. The first
+// bucket is assumed to start at the minimum of Range, or 0, and the +Inf
+// bucket to end at its maximum, or at the highest finite bound. Estimates are
+// clamped to Range. Quantiles of an empty histogram are NaN.
+func (h *HistogramMetric) Quantiles(quantiles []float64) map[float64]float64 {
+	bounds := h.Bounds()
+	out := make(map[float64]float64, len(quantiles))
+	for _, q := range quantiles {
+		out[q] = math.NaN()
+		if len(bounds) == 0 || h.Buckets[bounds[len(bounds)-1]] == 0 {
+			continue
+		}
+		out[q] = h.quantile(bounds, q)
+	}
+	return out
+}
+
+func (h *HistogramMetric) quantile(bounds []float64, q float64) float64 {
+	rank := q * float64(h.Buckets[bounds[len(bounds)-1]])
+	i, _ := slices.BinarySearchFunc(bounds, rank, func(bound, rank float64) int {
+		if float64(h.Buckets[bound]) < rank {
+			return -1
+		}
+		return 1
+	})
+	i = min(i, len(bounds)-1)
+
+	var lower float64
+	var lowerCount uint64
+	switch {
+	case i > 0:
+		lower, lowerCount = bounds[i-1], h.Buckets[bounds[i-1]]
+	case h.Range != nil:
+		lower = h.Range.Min
+	default:
+		lower = math.Min(0, bounds[0])
+	}
+	upper := bounds[i]
+	if math.IsInf(upper, 1) {
+		upper = lower
+		if h.Range != nil {
+			upper = h.Range.Max
+		}
+	}
+
+	value := upper
+	if count := h.Buckets[bounds[i]]; count > lowerCount {
+		value = lower + (upper-lower)*(rank-float64(lowerCount))/float64(count-lowerCount)
+	}
+	if h.Range != nil {
+		value = math.Max(h.Range.Min, math.Min(h.Range.Max, value))
+	}
+	return value
+}
+
+// Validate checks the invariants of a Prometheus histogram: the sum and the
+// bounds are numbers and bucket counts are cumulative.
+func (h *HistogramMetric) Validate() error {
+	if math.IsNaN(h.Sum) {
+		return fmt.Errorf("sum is NaN")
+	}
+	var previous uint64
+	for _, bound := range h.Bounds() {
+		if math.IsNaN(bound) {
+			return fmt.Errorf("bucket bound is NaN")
+		}
+		count := h.Buckets[bound]
+		if count < previous {
+			return fmt.Errorf("bucket %v has %d observations, less than the %d of the previous bucket", bound, count, previous)
+		}
+		previous = count
+	}
+	return nil
+}
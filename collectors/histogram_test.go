@@ -0,0 +1,180 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"maps"
+	"math"
+	"testing"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+func TestHistogramRebucket(t *testing.T) {
+	t.Parallel()
+
+	inf := math.Inf(1)
+	buckets := map[float64]uint64{10: 4, 20: 8, 40: 10, inf: 12}
+
+	tests := map[string]struct {
+		bounds []float64
+		want   map[float64]uint64
+	}{
+		"existing bounds": {
+			bounds: []float64{10, 40, inf},
+			want:   map[float64]uint64{10: 4, 40: 10, inf: 12},
+		},
+		"interpolated bounds": {
+			bounds: []float64{15, 30, inf},
+			want:   map[float64]uint64{15: 6, 30: 9, inf: 12},
+		},
+		"below the first bound": {
+			bounds: []float64{5, inf},
+			want:   map[float64]uint64{5: 2, inf: 12},
+		},
+		"between the last finite bound and infinity": {
+			bounds: []float64{100, inf},
+			want:   map[float64]uint64{100: 10, inf: 12},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := &HistogramMetric{Count: 12, Buckets: maps.Clone(buckets)}
+			h.Rebucket(test.bounds)
+			if !maps.Equal(h.Buckets, test.want) {
+				t.Errorf("Rebucket(%v) = %v, want %v", test.bounds, h.Buckets, test.want)
+			}
+			if err := h.Validate(); err != nil {
+				t.Errorf("Validate() after Rebucket(%v) = %v", test.bounds, err)
+			}
+		})
+	}
+}
+
+func TestHistogramValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		histogram HistogramMetric
+		wantErr   bool
+	}{
+		"cumulative buckets": {
+			histogram: HistogramMetric{Count: 3, Buckets: map[float64]uint64{1: 1, 2: 3, math.Inf(1): 3}},
+		},
+		"decreasing buckets": {
+			histogram: HistogramMetric{Count: 3, Buckets: map[float64]uint64{1: 2, 2: 1, math.Inf(1): 3}},
+			wantErr:   true,
+		},
+		"NaN sum": {
+			histogram: HistogramMetric{Sum: math.NaN(), Buckets: map[float64]uint64{math.Inf(1): 0}},
+			wantErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := test.histogram.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestBucketLayoutApply(t *testing.T) {
+	t.Parallel()
+
+	inf := math.Inf(1)
+	buckets := map[float64]uint64{1: 1, 2: 2, 4: 3, 8: 4, 16: 5, 32: 6, 64: 7, inf: 8}
+
+	tests := map[string]struct {
+		layout *BucketLayout
+		want   map[float64]uint64
+	}{
+		"no layout": {
+			want: buckets,
+		},
+		"explicit bounds": {
+			layout: &BucketLayout{Bounds: []float64{3, 48}},
+			want:   map[float64]uint64{3: 2, 48: 6, inf: 8},
+		},
+		"max buckets": {
+			layout: &BucketLayout{MaxBuckets: 4},
+			want:   map[float64]uint64{4: 3, 32: 6, inf: 8},
+		},
+		"fewer buckets than the maximum": {
+			layout: &BucketLayout{MaxBuckets: 8},
+			want:   buckets,
+		},
+		"a single finite bucket": {
+			layout: &BucketLayout{MaxBuckets: 2},
+			want:   map[float64]uint64{64: 7, inf: 8},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.layout.apply(maps.Clone(buckets)); !maps.Equal(got, test.want) {
+				t.Errorf("apply() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHistogramQuantiles(t *testing.T) {
+	t.Parallel()
+
+	inf := math.Inf(1)
+	buckets := map[float64]uint64{10: 4, 20: 8, 40: 10, inf: 12}
+	quantiles := []float64{0.25, 0.5, 0.875}
+
+	tests := map[string]struct {
+		histogram HistogramMetric
+		want      map[float64]float64
+	}{
+		"without range": {
+			histogram: HistogramMetric{Count: 12, Buckets: buckets},
+			want:      map[float64]float64{0.25: 7.5, 0.5: 15, 0.875: 40},
+		},
+		"with range": {
+			histogram: HistogramMetric{Count: 12, Buckets: buckets, Range: &monitoring.Range{Min: 2, Max: 100}},
+			want:      map[float64]float64{0.25: 8, 0.5: 15, 0.875: 55},
+		},
+		"clamped to range": {
+			histogram: HistogramMetric{Count: 12, Buckets: buckets, Range: &monitoring.Range{Min: 12, Max: 30}},
+			want:      map[float64]float64{0.25: 12, 0.5: 15, 0.875: 30},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.histogram.Quantiles(quantiles); !maps.Equal(got, test.want) {
+				t.Errorf("Quantiles() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	empty := HistogramMetric{Buckets: map[float64]uint64{10: 0, inf: 0}}
+	if got := empty.Quantiles(quantiles); !math.IsNaN(got[0.5]) {
+		t.Errorf("Quantiles() of an empty histogram = %v, want NaN", got)
+	}
+}
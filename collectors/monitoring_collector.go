@@ -15,16 +15,22 @@ package collectors
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"math"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+	"github.com/prometheus-community/stackdriver_exporter/relabel"
 )
 
 const namespace = "stackdriver"
@@ -61,10 +67,21 @@ func projectResource(projectID string) string {
 	return "projects/" + projectID
 }
 
+// targetResource returns the resource name of a collector target.
+func targetResource(target string) string {
+	if IsParentTarget(target) {
+		return target
+	}
+	return projectResource(target)
+}
+
 type MonitoringCollector struct {
 	projectID                       string
 	metricsTypePrefixes             []string
 	metricsFilters                  []MetricFilter
+	prefixConfigs                   []config.PrefixConfig
+	settings                        sync.Map
+	descriptorSelector              DescriptorSelector
 	metricsInterval                 time.Duration
 	metricsOffset                   time.Duration
 	metricsIngestDelay              bool
@@ -75,13 +92,32 @@ type MonitoringCollector struct {
 	lastScrapeErrorMetric           prometheus.Gauge
 	lastScrapeTimestampMetric       prometheus.Gauge
 	lastScrapeDurationSecondsMetric prometheus.Gauge
+	labelCollisionsTotalMetric      *prometheus.CounterVec
+	seriesDroppedTotalMetric        *prometheus.CounterVec
+	scopeSeriesMetric               *prometheus.GaugeVec
+	scopeLastSeenTimestampMetric    *prometheus.GaugeVec
+	maxSeriesPerMetric              int
+	labelCollisionStrategy          string
+	descriptorInfoDesc              *prometheus.Desc
+	cardinalityStats                bool
+	lastCardinalityStats            *CardinalityStats
+	lastCardinalityStatsLock        sync.Mutex
+	helpIncludeMetricType           bool
 	collectorFillMissingLabels      bool
 	monitoringDropDelegatedProjects bool
+	projectOwner                    ProjectOwner
+	descriptorProjectID             string
+	projectEnricher                 *ProjectEnricher
 	logger                          *slog.Logger
 	counterStore                    DeltaCounterStore
 	histogramStore                  DeltaHistogramStore
 	aggregateDeltas                 bool
+	deltaWindows                    *deltaWindows
 	descriptorCache                 DescriptorCache
+	sharedDescriptors               *sharedDescriptorCache
+	metricsScope                    *metricsScope
+	scopeLastSeenProjects           map[string]struct{}
+	scopeLastSeenProjectsLock       sync.Mutex
 }
 
 type MonitoringCollectorOptions struct {
@@ -91,8 +127,15 @@ type MonitoringCollectorOptions struct {
 	// ExtraFilters is a list of criteria to apply to each corresponding metric prefix query. If one or more are
 	// applicable to a given metric type prefix, they will be 'AND' concatenated.
 	ExtraFilters []MetricFilter
+	// PrefixConfigs are the settings of metric types starting with a prefix, such as relabel rules, metadata
+	// labels, series limits and how DELTA, GAUGE and DISTRIBUTION metrics are exported.
+	PrefixConfigs []config.PrefixConfig
+	// DescriptorSelector decides which of the metric descriptors found for MetricTypePrefixes are scraped. It is
+	// applied before descriptors are cached and before any time series are requested.
+	DescriptorSelector DescriptorSelector
 	// RequestInterval is the time interval used in each request to get metrics. If there are many data points returned
-	// during this interval, only the latest will be reported.
+	// during this interval, only the latest will be reported, except for aggregated DELTA metrics which add every
+	// point not seen before and widen the interval to reach back to the previous request.
 	RequestInterval time.Duration
 	// RequestOffset is used to offset the requested interval into the past.
 	RequestOffset time.Duration
@@ -103,12 +146,44 @@ type MonitoringCollectorOptions struct {
 	FillMissingLabels bool
 	// DropDelegatedProjects decides if only metrics matching the collector's projectID should be retrieved.
 	DropDelegatedProjects bool
+	// ProjectOwner, if set, drops series of monitored projects owned by another collector, so series shared
+	// through overlapping metrics scopes are exported once.
+	ProjectOwner ProjectOwner
+	// ScopeMetrics decides if per-project series counts are exported. It is set for the collector of a scoping
+	// project, whose scrape returns the series of every project in its metrics scope.
+	ScopeMetrics bool
+	// DescriptorProjectID is the project metric descriptors are listed from when the collector targets a folder
+	// or an organization.
+	DescriptorProjectID string
+	// ProjectEnricher, if set, adds project metadata labels to every series.
+	ProjectEnricher *ProjectEnricher
 	// AggregateDeltas decides if DELTA metrics should be treated as a counter using the provided counterStore/distributionStore or a gauge
 	AggregateDeltas bool
 	// DescriptorCacheTTL is the TTL on the items in the descriptorCache which caches the MetricDescriptors for a MetricTypePrefix
 	DescriptorCacheTTL time.Duration
 	// DescriptorCacheOnlyGoogle decides whether only google specific descriptors should be cached or all
 	DescriptorCacheOnlyGoogle bool
+	// LabelCollisionStrategy decides what happens when a monitored resource label has the same key as a metric
+	// label. One of the config.LabelCollision* values, empty means config.LabelCollisionDrop.
+	LabelCollisionStrategy string
+	// MaxSeriesPerMetric is the maximum number of series exported per metric type in a scrape, 0 means unlimited.
+	// Series over the limit are dropped by a stable hash of their labels.
+	MaxSeriesPerMetric int
+	// DescriptorInfoMetric decides if a stackdriver_metric_descriptor_info metric is exported for each scraped
+	// metric descriptor.
+	DescriptorInfoMetric bool
+	// HelpIncludeMetricType decides if the original metric type is appended to the HELP text of exported metrics.
+	HelpIncludeMetricType bool
+	// CardinalityStats decides if the series exported and API calls made by each scrape are recorded, see
+	// LastCardinalityStats.
+	CardinalityStats bool
+
+	// sharedDescriptors, if set, caches metric descriptors for every collector of a Runtime in place of
+	// a descriptor cache of the collector's own.
+	sharedDescriptors *sharedDescriptorCache
+	// metricsScope, if set with ScopeMetrics, is the metrics scope whose former projects are dropped from
+	// scope_last_seen_timestamp.
+	metricsScope *metricsScope
 }
 
 func isGoogleMetric(name string) bool {
@@ -144,9 +219,21 @@ type DeltaHistogramStore interface {
 	ListMetrics(metricDescriptorName string) []*HistogramMetric
 }
 
+// DeltaDescriptorForgetter is implemented by delta stores that can release
+// the series of a project that is no longer scraped before they expire.
+type DeltaDescriptorForgetter interface {
+	// ForgetDescriptors removes the series of every metric descriptor whose
+	// name starts with prefix.
+	ForgetDescriptors(prefix string)
+}
+
 func NewMonitoringCollector(projectID string, monitoringService *monitoring.Service, opts MonitoringCollectorOptions, logger *slog.Logger, counterStore DeltaCounterStore, histogramStore DeltaHistogramStore) (*MonitoringCollector, error) {
 	const subsystem = "monitoring"
 
+	if IsParentTarget(projectID) && opts.DescriptorProjectID == "" {
+		return nil, fmt.Errorf("collector for %s needs a descriptor project", projectID)
+	}
+
 	logger = logger.With("project_id", projectID)
 
 	apiCallsTotalMetric := prometheus.NewCounter(
@@ -209,8 +296,64 @@ func NewMonitoringCollector(projectID string, monitoringService *monitoring.Serv
 		},
 	)
 
+	labelCollisionsTotalMetric := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "label_collisions_total",
+			Help:        "Total number of monitored resource labels whose key collided with a metric label.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+		[]string{"metric_type"},
+	)
+
+	seriesDroppedTotalMetric := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   subsystem,
+			Name:        "series_dropped_total",
+			Help:        "Total number of series dropped because a metric type exceeded its series limit.",
+			ConstLabels: prometheus.Labels{"project_id": projectID},
+		},
+		[]string{"metric_type"},
+	)
+
+	var scopeSeriesMetric, scopeLastSeenTimestampMetric *prometheus.GaugeVec
+	if opts.ScopeMetrics {
+		scopeSeriesMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "scope_series",
+				Help:        "Number of series exported for each project of the metrics scope in the last scrape.",
+				ConstLabels: prometheus.Labels{"scoping_project_id": projectID},
+			},
+			[]string{"project_id"},
+		)
+		scopeLastSeenTimestampMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystem,
+				Name:        "scope_last_seen_timestamp",
+				Help:        "Last time a scrape exported series for a project of the metrics scope.",
+				ConstLabels: prometheus.Labels{"scoping_project_id": projectID},
+			},
+			[]string{"project_id"},
+		)
+	}
+
+	var descriptorInfoDesc *prometheus.Desc
+	if opts.DescriptorInfoMetric {
+		descriptorInfoDesc = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "metric_descriptor", "info"),
+			"Information about a scraped Google Stackdriver Monitoring metric descriptor.",
+			[]string{"type", "display_name", "metric_kind", "value_type", "unit", "launch_stage", "sample_period", "ingest_delay"},
+			prometheus.Labels{"project_id": projectID},
+		)
+	}
+
 	var descriptorCache DescriptorCache
-	if opts.DescriptorCacheTTL == 0 {
+	if opts.DescriptorCacheTTL == 0 || opts.sharedDescriptors != nil {
 		descriptorCache = &noopDescriptorCache{}
 	} else if opts.DescriptorCacheOnlyGoogle {
 		descriptorCache = &googleDescriptorCache{inner: newDescriptorCache(opts.DescriptorCacheTTL)}
@@ -223,6 +366,8 @@ func NewMonitoringCollector(projectID string, monitoringService *monitoring.Serv
 		projectID:                       projectID,
 		metricsTypePrefixes:             opts.MetricTypePrefixes,
 		metricsFilters:                  opts.ExtraFilters,
+		prefixConfigs:                   opts.PrefixConfigs,
+		descriptorSelector:              opts.DescriptorSelector,
 		metricsInterval:                 opts.RequestInterval,
 		metricsOffset:                   opts.RequestOffset,
 		metricsIngestDelay:              opts.IngestDelay,
@@ -233,13 +378,29 @@ func NewMonitoringCollector(projectID string, monitoringService *monitoring.Serv
 		lastScrapeErrorMetric:           lastScrapeErrorMetric,
 		lastScrapeTimestampMetric:       lastScrapeTimestampMetric,
 		lastScrapeDurationSecondsMetric: lastScrapeDurationSecondsMetric,
+		labelCollisionsTotalMetric:      labelCollisionsTotalMetric,
+		seriesDroppedTotalMetric:        seriesDroppedTotalMetric,
+		scopeSeriesMetric:               scopeSeriesMetric,
+		scopeLastSeenTimestampMetric:    scopeLastSeenTimestampMetric,
+		maxSeriesPerMetric:              opts.MaxSeriesPerMetric,
+		labelCollisionStrategy:          opts.LabelCollisionStrategy,
+		descriptorInfoDesc:              descriptorInfoDesc,
+		helpIncludeMetricType:           opts.HelpIncludeMetricType,
+		cardinalityStats:                opts.CardinalityStats,
 		collectorFillMissingLabels:      opts.FillMissingLabels,
 		monitoringDropDelegatedProjects: opts.DropDelegatedProjects,
+		projectOwner:                    opts.ProjectOwner,
+		descriptorProjectID:             opts.DescriptorProjectID,
+		projectEnricher:                 opts.ProjectEnricher,
 		logger:                          logger,
 		counterStore:                    counterStore,
 		histogramStore:                  histogramStore,
 		aggregateDeltas:                 opts.AggregateDeltas,
+		deltaWindows:                    newDeltaWindows(),
 		descriptorCache:                 descriptorCache,
+		sharedDescriptors:               opts.sharedDescriptors,
+		metricsScope:                    opts.metricsScope,
+		scopeLastSeenProjects:           make(map[string]struct{}),
 	}
 
 	return monitoringCollector, nil
@@ -252,6 +413,15 @@ func (c *MonitoringCollector) Describe(ch chan<- *prometheus.Desc) {
 	c.lastScrapeErrorMetric.Describe(ch)
 	c.lastScrapeTimestampMetric.Describe(ch)
 	c.lastScrapeDurationSecondsMetric.Describe(ch)
+	c.labelCollisionsTotalMetric.Describe(ch)
+	c.seriesDroppedTotalMetric.Describe(ch)
+	if c.scopeSeriesMetric != nil {
+		c.scopeSeriesMetric.Describe(ch)
+		c.scopeLastSeenTimestampMetric.Describe(ch)
+	}
+	if c.descriptorInfoDesc != nil {
+		ch <- c.descriptorInfoDesc
+	}
 }
 
 func (c *MonitoringCollector) Collect(ch chan<- prometheus.Metric) {
@@ -278,9 +448,43 @@ func (c *MonitoringCollector) Collect(ch chan<- prometheus.Metric) {
 
 	c.lastScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
 	c.lastScrapeDurationSecondsMetric.Collect(ch)
+
+	c.labelCollisionsTotalMetric.Collect(ch)
+	c.seriesDroppedTotalMetric.Collect(ch)
+	if c.scopeSeriesMetric != nil {
+		c.scopeSeriesMetric.Collect(ch)
+		c.scopeLastSeenTimestampMetric.Collect(ch)
+	}
+}
+
+// LastCardinalityStats returns the statistics of the last completed scrape, or
+// nil if CardinalityStats is disabled or no scrape has completed yet.
+func (c *MonitoringCollector) LastCardinalityStats() *CardinalityStats {
+	c.lastCardinalityStatsLock.Lock()
+	defer c.lastCardinalityStatsLock.Unlock()
+	return c.lastCardinalityStats
 }
 
 func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metric, begun time.Time) error {
+	drops := &seriesDrops{}
+	defer drops.warn(c.logger)
+
+	var stats *cardinalityRecorder
+	if c.cardinalityStats {
+		stats = newCardinalityRecorder()
+		defer func() {
+			c.lastCardinalityStatsLock.Lock()
+			defer c.lastCardinalityStatsLock.Unlock()
+			c.lastCardinalityStats = stats.stats(c.projectID, c.metricsTypePrefixes, begun)
+		}()
+	}
+
+	var scope *scopeSeriesRecorder
+	if c.scopeSeriesMetric != nil {
+		scope = newScopeSeriesRecorder()
+		defer c.reportScopeMetrics(scope, begun)
+	}
+
 	metricDescriptorsFunction := func(descriptors []*monitoring.MetricDescriptor) error {
 		var wg = &sync.WaitGroup{}
 
@@ -304,12 +508,16 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 		startTime := endTime.Add(c.metricsInterval * -1)
 
 		for _, metricDescriptor := range uniqueDescriptors {
+			if c.descriptorInfoDesc != nil {
+				ch <- c.descriptorInfoMetric(metricDescriptor)
+			}
+
 			wg.Add(1)
 			go func(metricDescriptor *monitoring.MetricDescriptor, ch chan<- prometheus.Metric, startTime, endTime time.Time) {
 				defer wg.Done()
 				c.logger.Debug("retrieving Google Stackdriver Monitoring metrics for descriptor", "descriptor", metricDescriptor.Type)
 				filter := fmt.Sprintf("metric.type=\"%s\"", metricDescriptor.Type)
-				if c.monitoringDropDelegatedProjects {
+				if c.monitoringDropDelegatedProjects && !IsParentTarget(c.projectID) {
 					filter = fmt.Sprintf(
 						"project=\"%s\" AND metric.type=\"%s\"",
 						c.projectID,
@@ -331,6 +539,13 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 					startTime = startTime.Add(ingestDelayDuration * -1)
 				}
 
+				// Every point of an aggregated DELTA series is added, so the window must not leave gaps
+				// between scrapes.
+				aggregatedDelta := metricDescriptor.MetricKind == "DELTA" && c.settingsFor(metricDescriptor.Type).deltaMode == config.DeltaModeAggregate
+				if aggregatedDelta {
+					startTime = c.deltaWindows.start(metricDescriptor, startTime, endTime)
+				}
+
 				for _, ef := range c.metricsFilters {
 					if strings.HasPrefix(metricDescriptor.Type, ef.TargetedMetricPrefix) {
 						filter = fmt.Sprintf("%s AND (%s)", filter, ef.FilterQuery)
@@ -339,31 +554,47 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 
 				c.logger.Debug("retrieving Google Stackdriver Monitoring metrics with filter", "filter", filter)
 
-				timeSeriesListCall := c.monitoringService.Projects.TimeSeries.List(projectResource(c.projectID)).
-					Filter(filter).
-					IntervalStartTime(startTime.Format(time.RFC3339Nano)).
-					IntervalEndTime(endTime.Format(time.RFC3339Nano))
+				// With a series limit all pages are needed to pick the same series on every scrape, so they are
+				// reported together once the last one has been received.
+				bufferPages := c.settingsFor(metricDescriptor.Type).maxSeries > 0
+				buffered := &monitoring.ListTimeSeriesResponse{}
 
+				var pageToken string
 				for {
 					c.apiCallsTotalMetric.Inc()
-					page, err := timeSeriesListCall.Do()
+					stats.addAPICall(metricDescriptor.Type)
+					page, err := c.listTimeSeriesPage(filter, startTime, endTime, pageToken)
 					if err != nil {
 						c.logger.Error("error retrieving Time Series metrics for descriptor", "descriptor", metricDescriptor.Type, "err", err)
 						errChannel <- err
-						break
+						return
 					}
 					if page == nil {
 						break
 					}
-					if err := c.reportTimeSeriesMetrics(page, metricDescriptor, ch, begun); err != nil {
+					if bufferPages {
+						buffered.TimeSeries = append(buffered.TimeSeries, page.TimeSeries...)
+					} else if err := c.reportTimeSeriesMetrics(page, metricDescriptor, ch, begun, drops, stats, scope); err != nil {
 						c.logger.Error("error reporting Time Series metrics for descriptor", "descriptor", metricDescriptor.Type, "err", err)
 						errChannel <- err
-						break
+						return
 					}
 					if page.NextPageToken == "" {
 						break
 					}
-					timeSeriesListCall.PageToken(page.NextPageToken)
+					pageToken = page.NextPageToken
+				}
+
+				if bufferPages {
+					if err := c.reportTimeSeriesMetrics(buffered, metricDescriptor, ch, begun, drops, stats, scope); err != nil {
+						c.logger.Error("error reporting Time Series metrics for descriptor", "descriptor", metricDescriptor.Type, "err", err)
+						errChannel <- err
+						return
+					}
+				}
+
+				if aggregatedDelta {
+					c.deltaWindows.done(metricDescriptor.Type, endTime)
 				}
 			}(metricDescriptor, ch, startTime, endTime)
 		}
@@ -383,17 +614,22 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 		go func(metricsTypePrefix string) {
 			defer wg.Done()
 			ctx := context.Background()
-			filter := fmt.Sprintf("metric.type = starts_with(\"%s\")", metricsTypePrefix)
-			if c.monitoringDropDelegatedProjects {
-				filter = fmt.Sprintf(
-					"project = \"%s\" AND metric.type = starts_with(\"%s\")",
-					c.projectID,
-					metricsTypePrefix)
-			}
+			listing := c.descriptorListing(metricsTypePrefix)
 
-			if cached := c.descriptorCache.Lookup(metricsTypePrefix); cached != nil {
+			if c.sharedDescriptors.cacheable(metricsTypePrefix) {
+				descriptors, err := c.sharedDescriptors.get(listing, func() {
+					c.apiCallsTotalMetric.Inc()
+					stats.addDescriptorListCall(metricsTypePrefix)
+				})
+				if err == nil {
+					err = metricDescriptorsFunction(descriptors)
+				}
+				if err != nil {
+					errChannel <- err
+				}
+			} else if cached := c.descriptorCache.Lookup(metricsTypePrefix); cached != nil {
 				c.logger.Debug("using cached Google Stackdriver Monitoring metric descriptors starting with", "prefix", metricsTypePrefix)
-				if err := metricDescriptorsFunction(cached); err != nil {
+				if err := metricDescriptorsFunction(c.descriptorSelector.Filter(cached)); err != nil {
 					errChannel <- err
 				}
 			} else {
@@ -401,13 +637,15 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 
 				callback := func(r *monitoring.ListMetricDescriptorsResponse) error {
 					c.apiCallsTotalMetric.Inc()
-					cache = append(cache, r.MetricDescriptors...)
-					return metricDescriptorsFunction(r.MetricDescriptors)
+					stats.addDescriptorListCall(metricsTypePrefix)
+					selected := c.descriptorSelector.Filter(r.MetricDescriptors)
+					cache = append(cache, selected...)
+					return metricDescriptorsFunction(selected)
 				}
 
 				c.logger.Debug("listing Google Stackdriver Monitoring metric descriptors starting with", "prefix", metricsTypePrefix)
-				if err := c.monitoringService.Projects.MetricDescriptors.List(projectResource(c.projectID)).
-					Filter(filter).
+				if err := c.monitoringService.Projects.MetricDescriptors.List(projectResource(listing.project)).
+					Filter(listing.filter()).
 					Pages(ctx, callback); err != nil {
 					errChannel <- err
 				}
@@ -424,60 +662,102 @@ func (c *MonitoringCollector) reportMonitoringMetrics(ch chan<- prometheus.Metri
 	return <-errChannel
 }
 
+// descriptorListing returns the listing of the metric descriptors starting
+// with prefix. With DropDelegatedProjects it only lists descriptors with data
+// in the collector's project.
+func (c *MonitoringCollector) descriptorListing(prefix string) descriptorListing {
+	listing := descriptorListing{project: c.descriptorProject(), prefix: prefix}
+	if c.monitoringDropDelegatedProjects && !IsParentTarget(c.projectID) {
+		listing.monitoredProject = c.projectID
+	}
+	return listing
+}
+
+// newDescriptorLister returns a descriptorLister listing descriptors with
+// service and keeping those selector selects.
+func newDescriptorLister(service *monitoring.Service, selector DescriptorSelector, logger *slog.Logger) descriptorLister {
+	return func(ctx context.Context, listing descriptorListing, onCall func()) ([]*monitoring.MetricDescriptor, error) {
+		logger.Debug("listing Google Stackdriver Monitoring metric descriptors starting with", "project", listing.project, "prefix", listing.prefix)
+		var descriptors []*monitoring.MetricDescriptor
+		err := service.Projects.MetricDescriptors.List(projectResource(listing.project)).
+			Filter(listing.filter()).
+			Pages(ctx, func(r *monitoring.ListMetricDescriptorsResponse) error {
+				onCall()
+				descriptors = append(descriptors, selector.Filter(r.MetricDescriptors)...)
+				return nil
+			})
+		return descriptors, err
+	}
+}
+
+func (c *MonitoringCollector) descriptorInfoMetric(metricDescriptor *monitoring.MetricDescriptor) prometheus.Metric {
+	var samplePeriod, ingestDelay string
+	if metricDescriptor.Metadata != nil {
+		samplePeriod = metricDescriptor.Metadata.SamplePeriod
+		ingestDelay = metricDescriptor.Metadata.IngestDelay
+	}
+	return prometheus.MustNewConstMetric(
+		c.descriptorInfoDesc,
+		prometheus.GaugeValue,
+		1,
+		metricDescriptor.Type,
+		metricDescriptor.DisplayName,
+		metricDescriptor.MetricKind,
+		metricDescriptor.ValueType,
+		metricDescriptor.Unit,
+		metricDescriptor.LaunchStage,
+		samplePeriod,
+		ingestDelay,
+	)
+}
+
 func (c *MonitoringCollector) reportTimeSeriesMetrics(
 	page *monitoring.ListTimeSeriesResponse,
 	metricDescriptor *monitoring.MetricDescriptor,
 	ch chan<- prometheus.Metric,
 	begun time.Time,
+	drops *seriesDrops,
+	stats *cardinalityRecorder,
+	scope *scopeSeriesRecorder,
 ) error {
 	var metricValue float64
 	var metricValueType prometheus.ValueType
 	var newestTSPoint *monitoring.Point
 
-	timeSeriesMetrics, err := newTimeSeriesMetrics(metricDescriptor,
+	settings := c.settingsFor(metricDescriptor.Type)
+	timeSeriesMetrics, err := newTimeSeriesMetrics(c.deltaDescriptor(metricDescriptor),
 		ch,
 		c.collectorFillMissingLabels,
 		c.counterStore,
 		c.histogramStore,
-		c.aggregateDeltas,
+		settings.deltaMode == config.DeltaModeAggregate,
+		c.helpIncludeMetricType,
+		settings.summaryQuantiles,
+		stats,
+		c.logger,
 	)
 	if err != nil {
 		return fmt.Errorf("error creating the TimeSeriesMetrics %v", err)
 	}
+	series := make([]*labeledTimeSeries, 0, len(page.TimeSeries))
 	for _, timeSeries := range page.TimeSeries {
-		newestEndTime := time.Unix(0, 0)
-		for _, point := range timeSeries.Points {
-			endTime, err := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
-			if err != nil {
-				return fmt.Errorf("error parsing TimeSeries Point interval end time `%s`: %s", point.Interval.EndTime, err)
-			}
-			if endTime.After(newestEndTime) {
-				newestEndTime = endTime
-				newestTSPoint = point
-			}
-		}
-		labelKeys := []string{"unit"}
-		labelValues := []string{metricDescriptor.Unit}
-
-		// Add the metric labels
-		// @see https://cloud.google.com/monitoring/api/metrics
-		for key, value := range timeSeries.Metric.Labels {
-			if !c.keyExists(labelKeys, key) {
-				labelKeys = append(labelKeys, key)
-				labelValues = append(labelValues, value)
-			}
+		if c.projectOwner != nil && !c.projectOwner.Owns(c.projectID, timeSeries.Resource.Labels["project_id"], begun) {
+			c.logger.Debug("dropping series of a project owned by another collector", "descriptor", metricDescriptor.Type, "monitored_project_id", timeSeries.Resource.Labels["project_id"])
+			continue
 		}
 
-		// Add the monitored resource labels
-		// @see https://cloud.google.com/monitoring/api/resources
-		for key, value := range timeSeries.Resource.Labels {
-			if !c.keyExists(labelKeys, key) {
-				labelKeys = append(labelKeys, key)
-				labelValues = append(labelValues, value)
-			}
+		labelKeys, labelValues, err := c.seriesLabels(metricDescriptor, timeSeries)
+		if err != nil {
+			return err
+		}
+		if len(settings.systemLabels) > 0 || len(settings.userLabels) > 0 {
+			labelKeys, labelValues = c.appendMetadataLabels(labelKeys, labelValues, timeSeries.Metadata, settings.systemLabels, settings.userLabels)
+		}
+		if c.projectEnricher != nil {
+			labelKeys, labelValues = c.projectEnricher.enrich(c.projectID, labelKeys, labelValues)
 		}
 
-		if c.monitoringDropDelegatedProjects {
+		if c.monitoringDropDelegatedProjects && !IsParentTarget(c.projectID) {
 			dropDelegatedProject := false
 
 			for idx, val := range labelKeys {
@@ -492,34 +772,79 @@ func (c *MonitoringCollector) reportTimeSeriesMetrics(
 			}
 		}
 
+		if len(settings.relabelConfigs) > 0 {
+			var keep bool
+			labelKeys, labelValues, keep = relabelLabels(buildFQName(timeSeries), labelKeys, labelValues, settings.relabelConfigs)
+			if !keep {
+				continue
+			}
+		}
+
+		series = append(series, &labeledTimeSeries{timeSeries: timeSeries, labelKeys: labelKeys, labelValues: labelValues})
+	}
+
+	if limit := settings.maxSeries; limit > 0 && len(series) > limit {
+		dropped := len(series) - limit
+		series = limitSeries(series, limit)
+		c.seriesDroppedTotalMetric.WithLabelValues(metricDescriptor.Type).Add(float64(dropped))
+		drops.add(metricDescriptor.Type, dropped)
+	}
+
+	for _, s := range series {
+		timeSeries, labelKeys, labelValues := s.timeSeries, s.labelKeys, s.labelValues
+		if scope != nil {
+			projectID := timeSeries.Resource.Labels["project_id"]
+			if projectID == "" {
+				projectID = c.projectID
+			}
+			scope.add(projectID)
+		}
+
+		if timeSeries.MetricKind == "DELTA" && settings.deltaMode == config.DeltaModeAggregate {
+			if err := c.collectDeltaPoints(timeSeriesMetrics, timeSeries, settings.bucketLayout, labelKeys, labelValues); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if timeSeries.MetricKind == "GAUGE" && timeSeries.ValueType != "DISTRIBUTION" && len(settings.gaugePolicies) > 0 {
+			if err := c.collectGaugePolicies(timeSeriesMetrics, timeSeries, settings.gaugePolicies, labelKeys, labelValues); err != nil {
+				c.logger.Debug("discarding", "resource", timeSeries.Resource.Type, "metric", timeSeries.Metric.Type, "err", err)
+			}
+			continue
+		}
+
+		newestEndTime := time.Unix(0, 0)
+		for _, point := range timeSeries.Points {
+			endTime, err := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
+			if err != nil {
+				return fmt.Errorf("error parsing TimeSeries Point interval end time `%s`: %s", point.Interval.EndTime, err)
+			}
+			if endTime.After(newestEndTime) {
+				newestEndTime = endTime
+				newestTSPoint = point
+			}
+		}
+
 		switch timeSeries.MetricKind {
-		case "GAUGE":
+		case "GAUGE", "DELTA":
 			metricValueType = prometheus.GaugeValue
-		case "DELTA":
-			if c.aggregateDeltas {
-				metricValueType = prometheus.CounterValue
-			} else {
-				metricValueType = prometheus.GaugeValue
-			}
 		case "CUMULATIVE":
 			metricValueType = prometheus.CounterValue
 		default:
 			continue
 		}
 
-		switch timeSeries.ValueType {
-		case "BOOL":
-			metricValue = 0
-			if *newestTSPoint.Value.BoolValue {
-				metricValue = 1
+		if timeSeries.MetricKind == "DELTA" && settings.deltaMode == config.DeltaModeRate {
+			if err := c.collectDeltaRate(timeSeriesMetrics, timeSeries, settings.bucketLayout, newestTSPoint, newestEndTime, labelKeys, labelValues); err != nil {
+				c.logger.Debug("discarding", "resource", timeSeries.Resource.Type, "metric", timeSeries.Metric.Type, "err", err)
 			}
-		case "INT64":
-			metricValue = float64(*newestTSPoint.Value.Int64Value)
-		case "DOUBLE":
-			metricValue = *newestTSPoint.Value.DoubleValue
-		case "DISTRIBUTION":
+			continue
+		}
+
+		if timeSeries.ValueType == "DISTRIBUTION" {
 			dist := newestTSPoint.Value.DistributionValue
-			buckets, err := c.generateHistogramBuckets(dist)
+			buckets, err := c.generateHistogramBuckets(dist, settings.bucketLayout)
 
 			if err == nil {
 				timeSeriesMetrics.CollectNewConstHistogram(timeSeries, newestEndTime, labelKeys, dist, buckets, labelValues, timeSeries.MetricKind)
@@ -528,7 +853,11 @@ func (c *MonitoringCollector) reportTimeSeriesMetrics(
 					timeSeries.Metric.Type, "err", err)
 			}
 			continue
-		default:
+		}
+
+		var ok bool
+		metricValue, ok = pointValue(timeSeries.ValueType, newestTSPoint)
+		if !ok {
 			c.logger.Debug("discarding", "value_type", timeSeries.ValueType, "metric", timeSeries)
 			continue
 		}
@@ -539,8 +868,139 @@ func (c *MonitoringCollector) reportTimeSeriesMetrics(
 	return nil
 }
 
+// deltaDescriptor returns a copy of a metric descriptor named after the
+// collector target rather than the project it was listed from. The delta
+// stores are keyed by descriptor name, so series of each target, and of each
+// project sharing a cached descriptor, are aggregated apart.
+func (c *MonitoringCollector) deltaDescriptor(metricDescriptor *monitoring.MetricDescriptor) *monitoring.MetricDescriptor {
+	descriptor := *metricDescriptor
+	descriptor.Name = targetResource(c.projectID) + "/metricDescriptors/" + metricDescriptor.Type
+	return &descriptor
+}
+
+// seriesLabels assembles the unit, metric and monitored resource labels of a
+// series, resolving resource labels that collide with metric labels according
+// to the configured strategy.
+func (c *MonitoringCollector) seriesLabels(metricDescriptor *monitoring.MetricDescriptor, timeSeries *monitoring.TimeSeries) ([]string, []string, error) {
+	labelKeys := []string{"unit"}
+	labelValues := []string{metricDescriptor.Unit}
+
+	// Add the metric labels
+	// @see https://cloud.google.com/monitoring/api/metrics
+	for key, value := range timeSeries.Metric.Labels {
+		if _, collides := timeSeries.Resource.Labels[key]; collides && c.labelCollisionStrategy == config.LabelCollisionPrefixMetric {
+			key = "metric_" + key
+		}
+		if !c.keyExists(labelKeys, key) {
+			labelKeys = append(labelKeys, key)
+			labelValues = append(labelValues, value)
+		}
+	}
+
+	// Add the monitored resource labels
+	// @see https://cloud.google.com/monitoring/api/resources
+	for key, value := range timeSeries.Resource.Labels {
+		if _, collides := timeSeries.Metric.Labels[key]; collides {
+			c.labelCollisionsTotalMetric.WithLabelValues(metricDescriptor.Type).Inc()
+			switch c.labelCollisionStrategy {
+			case config.LabelCollisionError:
+				return nil, nil, fmt.Errorf("resource label %q of %s collides with a metric label", key, timeSeries.Resource.Type)
+			case config.LabelCollisionPrefixResource:
+				key = "resource_" + key
+			}
+		}
+		if !c.keyExists(labelKeys, key) {
+			labelKeys = append(labelKeys, key)
+			labelValues = append(labelValues, value)
+		}
+	}
+
+	return labelKeys, labelValues, nil
+}
+
+// appendMetadataLabels adds the allowed system and user metadata labels of the
+// monitored resource. Allowed keys missing from the metadata are added with an
+// empty value so every series of a metric has the same label keys.
+// @see https://cloud.google.com/monitoring/api/ref_v3/rest/v3/MonitoredResourceMetadata
+func (c *MonitoringCollector) appendMetadataLabels(labelKeys, labelValues []string, metadata *monitoring.MonitoredResourceMetadata, systemLabels, userLabels []string) ([]string, []string) {
+	var system map[string]interface{}
+	var user map[string]string
+	if metadata != nil {
+		user = metadata.UserLabels
+		if len(systemLabels) > 0 && len(metadata.SystemLabels) > 0 {
+			if err := json.Unmarshal(metadata.SystemLabels, &system); err != nil {
+				c.logger.Debug("error parsing monitored resource system labels", "err", err)
+			}
+		}
+	}
+
+	for _, key := range systemLabels {
+		labelKey := "metadata_system_" + sanitizeLabelName(key)
+		if !c.keyExists(labelKeys, labelKey) {
+			labelKeys = append(labelKeys, labelKey)
+			labelValues = append(labelValues, systemLabelValue(system[key]))
+		}
+	}
+	for _, key := range userLabels {
+		labelKey := "metadata_user_" + sanitizeLabelName(key)
+		if !c.keyExists(labelKeys, labelKey) {
+			labelKeys = append(labelKeys, labelKey)
+			labelValues = append(labelValues, user[key])
+		}
+	}
+	return labelKeys, labelValues
+}
+
+// systemLabelValue flattens a system label value, which can be a string, a
+// boolean or a list of strings.
+func systemLabelValue(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case []interface{}:
+		parts := make([]string, 0, len(value))
+		for _, item := range value {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, ",")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// relabelLabels applies the relabel rules to a series. The exported metric
+// name is made available to the rules as __name__; like every other label
+// starting with "__" it is removed afterwards.
+func relabelLabels(fqName string, labelKeys, labelValues []string, cfgs []*relabel.Config) ([]string, []string, bool) {
+	keys := append(slices.Clone(labelKeys), "__name__")
+	values := append(slices.Clone(labelValues), fqName)
+
+	keys, values, keep := relabel.Process(keys, values, cfgs...)
+	if !keep {
+		return nil, nil, false
+	}
+
+	outKeys := make([]string, 0, len(keys))
+	outValues := make([]string, 0, len(values))
+	for i, key := range keys {
+		if strings.HasPrefix(key, "__") {
+			continue
+		}
+		outKeys = append(outKeys, key)
+		outValues = append(outValues, values[i])
+	}
+	return outKeys, outValues, true
+}
+
+// generateHistogramBuckets returns the cumulative buckets of a distribution,
+// re-bucketed onto layout unless it is nil.
 func (c *MonitoringCollector) generateHistogramBuckets(
 	dist *monitoring.Distribution,
+	layout *BucketLayout,
 ) (map[float64]uint64, error) {
 	opts := dist.BucketOptions
 	var bucketKeys []float64
@@ -587,7 +1047,7 @@ func (c *MonitoringCollector) generateHistogramBuckets(
 			buckets[b] = last
 		}
 	}
-	return buckets, nil
+	return layout.apply(buckets), nil
 }
 
 func (c *MonitoringCollector) keyExists(labelKeys []string, key string) bool {
@@ -14,8 +14,16 @@
 package collectors
 
 import (
+	"log/slog"
 	"reflect"
 	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+	"github.com/prometheus-community/stackdriver_exporter/relabel"
 )
 
 func TestIsGoogleMetric(t *testing.T) {
@@ -113,3 +121,204 @@ func TestProjectResource(t *testing.T) {
 		t.Fatalf("projectResource() = %q, want %q", got, "projects/fake-project-1")
 	}
 }
+
+func TestRelabelLabels(t *testing.T) {
+	t.Parallel()
+
+	cfgs := []*relabel.Config{
+		{Action: relabel.Drop, SourceLabels: []string{"__name__"}, Separator: ";", Regex: relabel.MustNewRegexp(".*_uptime")},
+		{Action: relabel.LabelDrop, Regex: relabel.MustNewRegexp("instance_id")},
+	}
+
+	keys, values, keep := relabelLabels("stackdriver_gce_instance_cpu", []string{"unit", "instance_id", "zone"}, []string{"s", "1234", "us-east1-b"}, cfgs)
+	if !keep {
+		t.Fatal("relabelLabels() dropped a series that should be kept")
+	}
+	if !reflect.DeepEqual(keys, []string{"unit", "zone"}) || !reflect.DeepEqual(values, []string{"s", "us-east1-b"}) {
+		t.Fatalf("relabelLabels() = (%v, %v), want __name__ and instance_id removed", keys, values)
+	}
+
+	if _, _, keep := relabelLabels("stackdriver_gce_instance_uptime", []string{"unit"}, []string{"s"}, cfgs); keep {
+		t.Fatal("relabelLabels() kept a series matching a drop rule on __name__")
+	}
+}
+
+func TestSeriesLabelsCollisionStrategies(t *testing.T) {
+	t.Parallel()
+
+	descriptor := &monitoring.MetricDescriptor{Type: "custom.googleapis.com/requests", Unit: "1"}
+	timeSeries := &monitoring.TimeSeries{
+		Metric:   &monitoring.Metric{Labels: map[string]string{"zone": "metric-zone"}},
+		Resource: &monitoring.MonitoredResource{Type: "gce_instance", Labels: map[string]string{"zone": "us-east1-b"}},
+	}
+
+	tests := []struct {
+		strategy string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			strategy: "",
+			want:     map[string]string{"unit": "1", "zone": "metric-zone"},
+		},
+		{
+			strategy: config.LabelCollisionDrop,
+			want:     map[string]string{"unit": "1", "zone": "metric-zone"},
+		},
+		{
+			strategy: config.LabelCollisionPrefixResource,
+			want:     map[string]string{"unit": "1", "zone": "metric-zone", "resource_zone": "us-east1-b"},
+		},
+		{
+			strategy: config.LabelCollisionPrefixMetric,
+			want:     map[string]string{"unit": "1", "metric_zone": "metric-zone", "zone": "us-east1-b"},
+		},
+		{
+			strategy: config.LabelCollisionError,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := NewMonitoringCollector("fake-project", nil, MonitoringCollectorOptions{LabelCollisionStrategy: tt.strategy}, slog.Default(), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, values, err := c.seriesLabels(descriptor, timeSeries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("seriesLabels() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if got := testutil.ToFloat64(c.labelCollisionsTotalMetric.WithLabelValues(descriptor.Type)); got != 1 {
+				t.Errorf("label_collisions_total = %v, want 1", got)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make(map[string]string, len(keys))
+			for i, key := range keys {
+				got[key] = values[i]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("seriesLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendMetadataLabels(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewMonitoringCollector("fake-project", nil, MonitoringCollectorOptions{}, slog.Default(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := &monitoring.MonitoredResourceMetadata{
+		SystemLabels: []byte(`{"name": "web-1", "spot_instance": false, "network_tags": ["a", "b"]}`),
+		UserLabels:   map[string]string{"team": "payments", "cost-center": "42"},
+	}
+
+	keys, values := c.appendMetadataLabels(
+		[]string{"unit"}, []string{"1"}, metadata,
+		[]string{"name", "network_tags", "spot_instance", "zone"},
+		[]string{"cost-center", "team"},
+	)
+	wantKeys := []string{"unit", "metadata_system_name", "metadata_system_network_tags", "metadata_system_spot_instance", "metadata_system_zone", "metadata_user_cost_center", "metadata_user_team"}
+	wantValues := []string{"1", "web-1", "a,b", "false", "", "42", "payments"}
+	if !reflect.DeepEqual(keys, wantKeys) || !reflect.DeepEqual(values, wantValues) {
+		t.Fatalf("appendMetadataLabels() = (%v, %v), want (%v, %v)", keys, values, wantKeys, wantValues)
+	}
+
+	keys, values = c.appendMetadataLabels([]string{"unit"}, []string{"1"}, nil, nil, []string{"team"})
+	if !reflect.DeepEqual(keys, []string{"unit", "metadata_user_team"}) || !reflect.DeepEqual(values, []string{"1", ""}) {
+		t.Fatalf("appendMetadataLabels() without metadata = (%v, %v)", keys, values)
+	}
+}
+
+func TestDescriptorInfoMetric(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewMonitoringCollector("fake-project", nil, MonitoringCollectorOptions{DescriptorInfoMetric: true}, slog.Default(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptor := &monitoring.MetricDescriptor{
+		Type:        "compute.googleapis.com/instance/cpu/usage_time",
+		DisplayName: "CPU usage",
+		MetricKind:  "DELTA",
+		ValueType:   "DOUBLE",
+		Unit:        "s{CPU}",
+		LaunchStage: "GA",
+		Metadata:    &monitoring.MetricDescriptorMetadata{SamplePeriod: "60s", IngestDelay: "240s"},
+	}
+
+	var m dto.Metric
+	if err := c.descriptorInfoMetric(descriptor).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, lp := range m.GetLabel() {
+		got[lp.GetName()] = lp.GetValue()
+	}
+	want := map[string]string{
+		"project_id":    "fake-project",
+		"type":          "compute.googleapis.com/instance/cpu/usage_time",
+		"display_name":  "CPU usage",
+		"metric_kind":   "DELTA",
+		"value_type":    "DOUBLE",
+		"unit":          "s{CPU}",
+		"launch_stage":  "GA",
+		"sample_period": "60s",
+		"ingest_delay":  "240s",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("descriptorInfoMetric() labels = %v, want %v", got, want)
+	}
+	if m.GetGauge().GetValue() != 1 {
+		t.Fatalf("descriptorInfoMetric() value = %v, want 1", m.GetGauge().GetValue())
+	}
+}
+
+// nopCounterStore and nopHistogramStore stand in for the delta stores when a
+// test does not aggregate DELTA metrics.
+type nopCounterStore struct{}
+
+func (nopCounterStore) Increment(*monitoring.MetricDescriptor, *ConstMetric) {}
+func (nopCounterStore) ListMetrics(string) []*ConstMetric                    { return nil }
+
+type nopHistogramStore struct{}
+
+func (nopHistogramStore) Increment(*monitoring.MetricDescriptor, *HistogramMetric) {}
+func (nopHistogramStore) ListMetrics(string) []*HistogramMetric                    { return nil }
+
+func TestDeltaDescriptorNamedAfterTarget(t *testing.T) {
+	t.Parallel()
+
+	// The descriptor was listed from, and may be cached for, another project.
+	const listedName = "projects/descriptors/metricDescriptors/custom.googleapis.com/requests"
+	descriptor := &monitoring.MetricDescriptor{Name: listedName, Type: "custom.googleapis.com/requests", MetricKind: "DELTA"}
+
+	tests := map[string]string{
+		"p":                "projects/p/metricDescriptors/custom.googleapis.com/requests",
+		"folders/1":        "folders/1/metricDescriptors/custom.googleapis.com/requests",
+		"organizations/10": "organizations/10/metricDescriptors/custom.googleapis.com/requests",
+	}
+	for target, want := range tests {
+		c, err := NewMonitoringCollector(target, nil, MonitoringCollectorOptions{DescriptorProjectID: "descriptors"}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := c.deltaDescriptor(descriptor)
+		if got.Name != want {
+			t.Errorf("deltaDescriptor().Name for %s = %q, want %q", target, got.Name, want)
+		}
+		if got.MetricKind != descriptor.MetricKind {
+			t.Errorf("deltaDescriptor() lost the metric kind")
+		}
+	}
+	if descriptor.Name != listedName {
+		t.Errorf("deltaDescriptor() modified the descriptor, Name = %q", descriptor.Name)
+	}
+}
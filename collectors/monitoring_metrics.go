@@ -14,6 +14,9 @@
 package collectors
 
 import (
+	"fmt"
+	"log/slog"
+	"math"
 	"regexp"
 	"sort"
 	"strings"
@@ -26,7 +29,10 @@ import (
 	"github.com/prometheus-community/stackdriver_exporter/hash"
 )
 
-var safeNameRE = regexp.MustCompile(`[^a-zA-Z0-9_]*$`)
+var (
+	safeNameRE       = regexp.MustCompile(`[^a-zA-Z0-9_]*$`)
+	invalidLabelChar = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
 
 func buildFQName(timeSeries *monitoring.TimeSeries) string {
 	// The metric name to report is composed by the 3 parts:
@@ -48,6 +54,12 @@ func normalizeMetricName(metricName string) string {
 	return strings.Join(parts, "_")
 }
 
+// sanitizeLabelName replaces every character that is not allowed in a
+// Prometheus label name with an underscore.
+func sanitizeLabelName(name string) string {
+	return invalidLabelChar.ReplaceAllString(name, "_")
+}
+
 type timeSeriesMetrics struct {
 	metricDescriptor *monitoring.MetricDescriptor
 
@@ -60,6 +72,14 @@ type timeSeriesMetrics struct {
 	counterStore    DeltaCounterStore
 	histogramStore  DeltaHistogramStore
 	aggregateDeltas bool
+
+	help string
+
+	summaryQuantiles []float64
+
+	stats *cardinalityRecorder
+
+	logger *slog.Logger
 }
 
 func newTimeSeriesMetrics(descriptor *monitoring.MetricDescriptor,
@@ -67,7 +87,16 @@ func newTimeSeriesMetrics(descriptor *monitoring.MetricDescriptor,
 	fillMissingLabels bool,
 	counterStore DeltaCounterStore,
 	histogramStore DeltaHistogramStore,
-	aggregateDeltas bool) (*timeSeriesMetrics, error) {
+	aggregateDeltas bool,
+	helpIncludeMetricType bool,
+	summaryQuantiles []float64,
+	stats *cardinalityRecorder,
+	logger *slog.Logger) (*timeSeriesMetrics, error) {
+
+	help := descriptor.Description
+	if helpIncludeMetricType {
+		help = strings.TrimSpace(fmt.Sprintf("%s Metric type: %s", help, descriptor.Type))
+	}
 
 	return &timeSeriesMetrics{
 		metricDescriptor:  descriptor,
@@ -78,13 +107,17 @@ func newTimeSeriesMetrics(descriptor *monitoring.MetricDescriptor,
 		counterStore:      counterStore,
 		histogramStore:    histogramStore,
 		aggregateDeltas:   aggregateDeltas,
+		help:              help,
+		summaryQuantiles:  summaryQuantiles,
+		stats:             stats,
+		logger:            logger,
 	}, nil
 }
 
 func (t *timeSeriesMetrics) newMetricDesc(fqName string, labelKeys []string) *prometheus.Desc {
 	return prometheus.NewDesc(
 		fqName,
-		t.metricDescriptor.Description,
+		t.help,
 		labelKeys,
 		prometheus.Labels{},
 	)
@@ -98,6 +131,8 @@ type ConstMetric struct {
 	LabelValues    []string
 	ReportTime     time.Time
 	CollectionTime time.Time
+	// StartTime is the start of the interval of the first point of an aggregated DELTA series, when known.
+	StartTime time.Time
 
 	KeysHash uint64
 }
@@ -111,6 +146,10 @@ type HistogramMetric struct {
 	LabelValues    []string
 	ReportTime     time.Time
 	CollectionTime time.Time
+	// StartTime is the start of the interval of the first point of an aggregated DELTA series, when known.
+	StartTime time.Time
+	// Range is the range of the observations of the distribution, when known.
+	Range *monitoring.Range
 
 	KeysHash uint64
 }
@@ -120,6 +159,13 @@ func (h *HistogramMetric) MergeHistogram(other *HistogramMetric) {
 	h.Sum += other.Sum
 	h.Count += other.Count
 
+	// The merged range is only known when both ranges are.
+	if h.Range != nil && other.Range != nil {
+		h.Range = &monitoring.Range{Min: math.Min(h.Range.Min, other.Range.Min), Max: math.Max(h.Range.Max, other.Range.Max)}
+	} else {
+		h.Range = nil
+	}
+
 	// Merge the buckets from existing in to current
 	for key, value := range other.Buckets {
 		h.Buckets[key] += value
@@ -129,8 +175,13 @@ func (h *HistogramMetric) MergeHistogram(other *HistogramMetric) {
 func (t *timeSeriesMetrics) CollectNewConstHistogram(timeSeries *monitoring.TimeSeries, reportTime time.Time, labelKeys []string, dist *monitoring.Distribution, buckets map[float64]uint64, labelValues []string, metricKind string) {
 	fqName := buildFQName(timeSeries)
 	histogramSum := dist.Mean * float64(dist.Count)
+	if metricKind == "DELTA" && t.aggregateDeltas {
+		t.incrementDeltaHistogram(timeSeries, time.Time{}, reportTime, labelKeys, dist, buckets, labelValues)
+		return
+	}
+
 	var v HistogramMetric
-	if t.fillMissingLabels || (metricKind == "DELTA" && t.aggregateDeltas) {
+	if t.fillMissingLabels {
 		v = HistogramMetric{
 			FqName:         fqName,
 			LabelKeys:      labelKeys,
@@ -140,16 +191,12 @@ func (t *timeSeriesMetrics) CollectNewConstHistogram(timeSeries *monitoring.Time
 			LabelValues:    labelValues,
 			ReportTime:     reportTime,
 			CollectionTime: time.Now(),
+			Range:          dist.Range,
 
 			KeysHash: hashLabelKeys(labelKeys),
 		}
 	}
 
-	if metricKind == "DELTA" && t.aggregateDeltas {
-		t.histogramStore.Increment(t.metricDescriptor, &v)
-		return
-	}
-
 	if t.fillMissingLabels {
 		vs, ok := t.histogramMetrics[fqName]
 		if !ok {
@@ -159,10 +206,41 @@ func (t *timeSeriesMetrics) CollectNewConstHistogram(timeSeries *monitoring.Time
 		return
 	}
 
-	t.ch <- t.newConstHistogram(fqName, reportTime, labelKeys, histogramSum, uint64(dist.Count), buckets, labelValues)
+	t.sendHistogram(fqName, reportTime, labelKeys, histogramSum, uint64(dist.Count), buckets, dist.Range, labelValues)
+}
+
+// sendHistogram exports a histogram, or a summary when summary quantiles are
+// configured, unless it breaks the histogram invariants, which would fail the
+// whole scrape.
+func (t *timeSeriesMetrics) sendHistogram(fqName string, reportTime time.Time, labelKeys []string, sum float64, count uint64, buckets map[float64]uint64, valueRange *monitoring.Range, labelValues []string) {
+	h := HistogramMetric{Sum: sum, Count: count, Buckets: buckets, Range: valueRange}
+	if err := h.Validate(); err != nil {
+		t.logger.Warn("dropping invalid histogram", "fqName", fqName, "labels", labelValues, "err", err)
+		return
+	}
+	if len(t.summaryQuantiles) > 0 {
+		t.ch <- t.newConstSummary(fqName, reportTime, labelKeys, sum, count, h.Quantiles(t.summaryQuantiles), labelValues)
+		return
+	}
+	t.ch <- t.newConstHistogram(fqName, reportTime, labelKeys, sum, count, buckets, labelValues)
+}
+
+func (t *timeSeriesMetrics) newConstSummary(fqName string, reportTime time.Time, labelKeys []string, sum float64, count uint64, quantiles map[float64]float64, labelValues []string) prometheus.Metric {
+	t.stats.observe(fqName, labelKeys, labelValues)
+	return prometheus.NewMetricWithTimestamp(
+		reportTime,
+		prometheus.MustNewConstSummary(
+			t.newMetricDesc(fqName, labelKeys),
+			count,
+			sum,
+			quantiles,
+			labelValues...,
+		),
+	)
 }
 
 func (t *timeSeriesMetrics) newConstHistogram(fqName string, reportTime time.Time, labelKeys []string, sum float64, count uint64, buckets map[float64]uint64, labelValues []string) prometheus.Metric {
+	t.stats.observe(fqName, labelKeys, labelValues)
 	return prometheus.NewMetricWithTimestamp(
 		reportTime,
 		prometheus.MustNewConstHistogram(
@@ -176,10 +254,17 @@ func (t *timeSeriesMetrics) newConstHistogram(fqName string, reportTime time.Tim
 }
 
 func (t *timeSeriesMetrics) CollectNewConstMetric(timeSeries *monitoring.TimeSeries, reportTime time.Time, labelKeys []string, metricValueType prometheus.ValueType, metricValue float64, labelValues []string, metricKind string) {
-	fqName := buildFQName(timeSeries)
+	if metricKind == "DELTA" && t.aggregateDeltas {
+		t.incrementDeltaConstMetric(timeSeries, time.Time{}, reportTime, labelKeys, metricValueType, metricValue, labelValues)
+		return
+	}
+	t.collectConstMetric(buildFQName(timeSeries), reportTime, labelKeys, metricValueType, metricValue, labelValues)
+}
 
+// collectConstMetric exports a sample, or keeps it to fill missing labels on Complete.
+func (t *timeSeriesMetrics) collectConstMetric(fqName string, reportTime time.Time, labelKeys []string, metricValueType prometheus.ValueType, metricValue float64, labelValues []string) {
 	var v ConstMetric
-	if t.fillMissingLabels || (metricKind == "DELTA" && t.aggregateDeltas) {
+	if t.fillMissingLabels {
 		v = ConstMetric{
 			FqName:         fqName,
 			LabelKeys:      labelKeys,
@@ -193,11 +278,6 @@ func (t *timeSeriesMetrics) CollectNewConstMetric(timeSeries *monitoring.TimeSer
 		}
 	}
 
-	if metricKind == "DELTA" && t.aggregateDeltas {
-		t.counterStore.Increment(t.metricDescriptor, &v)
-		return
-	}
-
 	if t.fillMissingLabels {
 		vs, ok := t.constMetrics[fqName]
 		if !ok {
@@ -210,7 +290,44 @@ func (t *timeSeriesMetrics) CollectNewConstMetric(timeSeries *monitoring.TimeSer
 	t.ch <- t.newConstMetric(fqName, reportTime, labelKeys, metricValueType, metricValue, labelValues)
 }
 
+// incrementDeltaConstMetric adds a point of an aggregated DELTA series, whose
+// interval runs from startTime to reportTime, to the counter store.
+func (t *timeSeriesMetrics) incrementDeltaConstMetric(timeSeries *monitoring.TimeSeries, startTime, reportTime time.Time, labelKeys []string, metricValueType prometheus.ValueType, metricValue float64, labelValues []string) {
+	t.counterStore.Increment(t.metricDescriptor, &ConstMetric{
+		FqName:         buildFQName(timeSeries),
+		LabelKeys:      labelKeys,
+		ValueType:      metricValueType,
+		Value:          metricValue,
+		LabelValues:    labelValues,
+		ReportTime:     reportTime,
+		CollectionTime: time.Now(),
+		StartTime:      startTime,
+
+		KeysHash: hashLabelKeys(labelKeys),
+	})
+}
+
+// incrementDeltaHistogram adds a point of an aggregated DELTA distribution,
+// whose interval runs from startTime to reportTime, to the histogram store.
+func (t *timeSeriesMetrics) incrementDeltaHistogram(timeSeries *monitoring.TimeSeries, startTime, reportTime time.Time, labelKeys []string, dist *monitoring.Distribution, buckets map[float64]uint64, labelValues []string) {
+	t.histogramStore.Increment(t.metricDescriptor, &HistogramMetric{
+		FqName:         buildFQName(timeSeries),
+		LabelKeys:      labelKeys,
+		Sum:            dist.Mean * float64(dist.Count),
+		Count:          uint64(dist.Count),
+		Buckets:        buckets,
+		LabelValues:    labelValues,
+		ReportTime:     reportTime,
+		CollectionTime: time.Now(),
+		StartTime:      startTime,
+		Range:          dist.Range,
+
+		KeysHash: hashLabelKeys(labelKeys),
+	})
+}
+
 func (t *timeSeriesMetrics) newConstMetric(fqName string, reportTime time.Time, labelKeys []string, metricValueType prometheus.ValueType, metricValue float64, labelValues []string) prometheus.Metric {
+	t.stats.observe(fqName, labelKeys, labelValues)
 	return prometheus.NewMetricWithTimestamp(
 		reportTime,
 		prometheus.MustNewConstMetric(
@@ -275,7 +392,7 @@ func (t *timeSeriesMetrics) completeHistogramMetrics(histograms map[string][]*Hi
 			}
 		}
 		for _, v := range vs {
-			t.ch <- t.newConstHistogram(v.FqName, v.ReportTime, v.LabelKeys, v.Sum, v.Count, v.Buckets, v.LabelValues)
+			t.sendHistogram(v.FqName, v.ReportTime, v.LabelKeys, v.Sum, v.Count, v.Buckets, v.Range, v.LabelValues)
 		}
 	}
 }
@@ -336,13 +453,14 @@ func (t *timeSeriesMetrics) completeDeltaHistogramMetrics(reportingStartTime tim
 			}
 			histograms[collected.FqName] = append(histograms[collected.FqName], collected)
 		} else {
-			t.ch <- t.newConstHistogram(
+			t.sendHistogram(
 				collected.FqName,
 				collected.ReportTime,
 				collected.LabelKeys,
 				collected.Sum,
 				collected.Count,
 				collected.Buckets,
+				collected.Range,
 				collected.LabelValues,
 			)
 		}
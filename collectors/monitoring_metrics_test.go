@@ -13,7 +13,19 @@
 
 package collectors
 
-import "testing"
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/api/monitoring/v3"
+)
 
 func TestNormalizeMetricName(t *testing.T) {
 	t.Parallel()
@@ -24,3 +36,112 @@ func TestNormalizeMetricName(t *testing.T) {
 		t.Fatalf("normalizeMetricName() = %q, want %q", got, want)
 	}
 }
+
+func TestTimeSeriesMetricsHelp(t *testing.T) {
+	t.Parallel()
+
+	descriptor := &monitoring.MetricDescriptor{
+		Type:        "compute.googleapis.com/instance/cpu/usage_time",
+		Description: "Delta vCPU usage.",
+	}
+
+	tests := []struct {
+		name                  string
+		helpIncludeMetricType bool
+		want                  string
+	}{
+		{
+			name: "description only",
+			want: "Delta vCPU usage.",
+		},
+		{
+			name:                  "description with metric type",
+			helpIncludeMetricType: true,
+			want:                  "Delta vCPU usage. Metric type: compute.googleapis.com/instance/cpu/usage_time",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tsm, err := newTimeSeriesMetrics(descriptor, nil, false, nil, nil, false, tt.helpIncludeMetricType, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			desc := tsm.newMetricDesc("stackdriver_gce_instance_compute_googleapis_com_instance_cpu_usage_time", nil)
+			if !strings.Contains(desc.String(), fmt.Sprintf("help: %q", tt.want)) {
+				t.Fatalf("newMetricDesc() = %s, want help %q", desc, tt.want)
+			}
+		})
+	}
+}
+
+// listHistogramStore lists the same histograms for every descriptor.
+type listHistogramStore []*HistogramMetric
+
+func (listHistogramStore) Increment(*monitoring.MetricDescriptor, *HistogramMetric) {}
+func (s listHistogramStore) ListMetrics(string) []*HistogramMetric                  { return s }
+
+func TestTimeSeriesMetricsSummary(t *testing.T) {
+	t.Parallel()
+
+	inf := math.Inf(1)
+	timeSeries := &monitoring.TimeSeries{
+		Metric:   &monitoring.Metric{Type: "custom.googleapis.com/latency"},
+		Resource: &monitoring.MonitoredResource{Type: "gce_instance"},
+	}
+	dist := &monitoring.Distribution{Count: 12, Mean: 15, Range: &monitoring.Range{Min: 2, Max: 100}}
+	buckets := map[float64]uint64{10: 4, 20: 8, 40: 10, inf: 12}
+	descriptor := &monitoring.MetricDescriptor{Name: "projects/p/metricDescriptors/custom.googleapis.com/latency"}
+
+	tests := map[string]func(*timeSeriesMetrics){
+		"gauge distribution": func(tsm *timeSeriesMetrics) {
+			tsm.CollectNewConstHistogram(timeSeries, time.Now(), []string{"unit"}, dist, buckets, []string{"ms"}, "GAUGE")
+		},
+		"aggregated DELTA distribution": func(tsm *timeSeriesMetrics) {
+			tsm.histogramStore = listHistogramStore{{
+				FqName:         buildFQName(timeSeries),
+				LabelKeys:      []string{"unit"},
+				Sum:            180,
+				Count:          12,
+				Buckets:        buckets,
+				LabelValues:    []string{"ms"},
+				ReportTime:     time.Now(),
+				CollectionTime: time.Now(),
+				Range:          dist.Range,
+			}}
+		},
+	}
+
+	for name, collect := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ch := make(chan prometheus.Metric, 1)
+			tsm, err := newTimeSeriesMetrics(descriptor, ch, false, nopCounterStore{}, nopHistogramStore{}, true, false, []float64{0.5, 0.875}, nil, slog.Default())
+			if err != nil {
+				t.Fatal(err)
+			}
+			collect(tsm)
+			tsm.Complete(time.Now())
+			close(ch)
+
+			var m dto.Metric
+			if err := (<-ch).Write(&m); err != nil {
+				t.Fatal(err)
+			}
+			summary := m.GetSummary()
+			if summary.GetSampleCount() != 12 || summary.GetSampleSum() != 180 {
+				t.Fatalf("summary count and sum = %d, %v, want 12, 180", summary.GetSampleCount(), summary.GetSampleSum())
+			}
+			got := make(map[float64]float64)
+			for _, q := range summary.GetQuantile() {
+				got[q.GetQuantile()] = q.GetValue()
+			}
+			if want := map[float64]float64{0.5: 15, 0.875: 55}; !maps.Equal(got, want) {
+				t.Errorf("summary quantiles = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"sync"
+	"time"
+)
+
+// ProjectOwner decides which collector exports the series of a monitored
+// project when the metrics scopes of several collectors include it.
+type ProjectOwner interface {
+	// Owns reports whether the collector for collectorProjectID should export
+	// series whose project_id resource label is monitoredProjectID in its
+	// scrape begun at scrapeStart.
+	Owns(collectorProjectID, monitoredProjectID string, scrapeStart time.Time) bool
+}
+
+// projectOwnership assigns every monitored project to a single collector.
+// A configured project is always owned by its own collector. Any other
+// project is owned by the lexicographically smallest collector that saw it in
+// the last claimTTL. The first collector seeing a project claims it at once;
+// a smaller collector seeing it later takes over for the scrapes begun after
+// the handover, so both never export it in the same scrape.
+type projectOwnership struct {
+	lock       sync.Mutex
+	claimTTL   time.Duration
+	configured map[string]struct{}
+	claims     map[string]*ownershipClaim
+}
+
+type ownershipClaim struct {
+	owner string
+	// previous owns the scrapes begun before since.
+	previous string
+	since    time.Time
+	lastSeen map[string]time.Time
+}
+
+func newProjectOwnership(projectIDs []string, claimTTL time.Duration) *projectOwnership {
+	o := &projectOwnership{claimTTL: claimTTL, claims: make(map[string]*ownershipClaim)}
+	o.setConfigured(projectIDs)
+	return o
+}
+
+// setConfigured replaces the projects that have a collector of their own.
+func (o *projectOwnership) setConfigured(projectIDs []string) {
+	configured := make(map[string]struct{}, len(projectIDs))
+	for _, id := range projectIDs {
+		configured[id] = struct{}{}
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.configured = configured
+}
+
+// forgetCollector releases the claims of a collector that was removed, so
+// the projects it owned go to the smallest collector still seeing them.
+func (o *projectOwnership) forgetCollector(collectorProjectID string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	for monitoredProjectID, claim := range o.claims {
+		delete(claim.lastSeen, collectorProjectID)
+		if claim.previous == collectorProjectID {
+			claim.previous, claim.since = "", time.Time{}
+		}
+		if claim.owner != collectorProjectID {
+			continue
+		}
+		if len(claim.lastSeen) == 0 {
+			delete(o.claims, monitoredProjectID)
+			continue
+		}
+		claim.owner = ""
+		for id := range claim.lastSeen {
+			if claim.owner == "" || id < claim.owner {
+				claim.owner = id
+			}
+		}
+	}
+}
+
+func (o *projectOwnership) Owns(collectorProjectID, monitoredProjectID string, scrapeStart time.Time) bool {
+	if monitoredProjectID == "" || monitoredProjectID == collectorProjectID {
+		return true
+	}
+
+	now := time.Now()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if _, ok := o.configured[monitoredProjectID]; ok {
+		return false
+	}
+
+	claim, ok := o.claims[monitoredProjectID]
+	if !ok {
+		claim = &ownershipClaim{owner: collectorProjectID, lastSeen: make(map[string]time.Time)}
+		o.claims[monitoredProjectID] = claim
+	}
+	claim.lastSeen[collectorProjectID] = now
+
+	smallest := collectorProjectID
+	for id, seen := range claim.lastSeen {
+		if now.Sub(seen) > o.claimTTL {
+			delete(claim.lastSeen, id)
+			continue
+		}
+		smallest = min(smallest, id)
+	}
+	if smallest != claim.owner {
+		if _, ok := claim.lastSeen[claim.owner]; ok {
+			// The owner may be exporting the project in this very scrape.
+			claim.previous, claim.since = claim.owner, now
+		} else {
+			claim.previous, claim.since = "", time.Time{}
+		}
+		claim.owner = smallest
+	}
+
+	if scrapeStart.Before(claim.since) {
+		return claim.previous == collectorProjectID
+	}
+	return claim.owner == collectorProjectID
+}
@@ -0,0 +1,95 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProjectOwnership(t *testing.T) {
+	t.Parallel()
+
+	const ttl = time.Hour
+	o := newProjectOwnership([]string{"scope-a", "scope-b", "member"}, ttl)
+	scrape := time.Now()
+
+	if !o.Owns("scope-a", "", scrape) {
+		t.Error("series without project_id should always be kept")
+	}
+	if !o.Owns("scope-a", "scope-a", scrape) {
+		t.Error("a collector should own its own project")
+	}
+	if o.Owns("scope-a", "member", scrape) {
+		t.Error("a configured project should only be owned by its own collector")
+	}
+	if !o.Owns("member", "member", scrape) {
+		t.Error("a configured project should be owned by its own collector")
+	}
+
+	if !o.Owns("scope-b", "shared", scrape) {
+		t.Error("the first collector seeing an unconfigured project should claim it")
+	}
+	if o.Owns("scope-a", "shared", scrape) {
+		t.Error("a smaller collector should not take over during the scrape the project was claimed in")
+	}
+	if !o.Owns("scope-b", "shared", scrape) {
+		t.Error("the claiming collector should keep the project for the scrape in progress")
+	}
+
+	scrape = time.Now()
+	if o.Owns("scope-b", "shared", scrape) {
+		t.Error("the claiming collector should hand the project over to the smaller collector in the next scrape")
+	}
+	if !o.Owns("scope-a", "shared", scrape) {
+		t.Error("the smallest collector seeing the project should own it in the next scrape")
+	}
+
+	o.claims["shared"].lastSeen["scope-a"] = time.Now().Add(-2 * ttl)
+	scrape = time.Now()
+	if !o.Owns("scope-b", "shared", scrape) {
+		t.Error("a collector should take over a project the owner stopped seeing")
+	}
+	if o.Owns("scope-a", "shared", scrape) {
+		t.Error("the previous owner should not keep a project it stopped seeing during the takeover scrape")
+	}
+}
+
+func TestProjectOwnershipIsOrderIndependent(t *testing.T) {
+	t.Parallel()
+
+	collectors := []string{"scope-c", "scope-a", "scope-b"}
+	for _, first := range collectors {
+		o := newProjectOwnership(nil, time.Hour)
+		order := append([]string{first}, collectors...)
+		for range 2 {
+			scrape := time.Now()
+			for _, c := range order {
+				o.Owns(c, "shared", scrape)
+			}
+		}
+
+		scrape := time.Now()
+		var owners []string
+		for _, c := range order {
+			if o.Owns(c, "shared", scrape) && !slices.Contains(owners, c) {
+				owners = append(owners, c)
+			}
+		}
+		if !slices.Equal(owners, []string{"scope-a"}) {
+			t.Errorf("first seen by %s: owners = %v, want [scope-a]", first, owners)
+		}
+	}
+}
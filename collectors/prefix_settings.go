@@ -0,0 +1,102 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+	"github.com/prometheus-community/stackdriver_exporter/relabel"
+)
+
+// metricSettings are the per-prefix settings in effect for a metric type.
+type metricSettings struct {
+	relabelConfigs []*relabel.Config
+	systemLabels   []string
+	userLabels     []string
+	// maxSeries is the series limit, 0 if unlimited.
+	maxSeries int
+	// deltaMode is how DELTA metrics are exported, one of config.DeltaModes.
+	deltaMode string
+	// gaugePolicies are the values exported for GAUGE series, nil to export the newest point.
+	gaugePolicies []config.GaugePolicyConfig
+	// bucketLayout re-buckets distributions, nil to keep their buckets.
+	bucketLayout *BucketLayout
+	// summaryQuantiles, if set, exports distributions as summaries instead of histograms.
+	summaryQuantiles []float64
+}
+
+// settingsFor returns the settings of a metric type. They are resolved from
+// the prefix configs once per metric type: relabel rules and metadata labels of
+// every matching prefix are combined in file order, every other setting is
+// taken from the longest matching prefix setting it, falling back to the
+// collector-wide default.
+func (c *MonitoringCollector) settingsFor(metricType string) *metricSettings {
+	if s, ok := c.settings.Load(metricType); ok {
+		return s.(*metricSettings)
+	}
+
+	var matches []*config.PrefixConfig
+	s := &metricSettings{}
+	for i := range c.prefixConfigs {
+		pc := &c.prefixConfigs[i]
+		if !strings.HasPrefix(metricType, pc.Prefix) {
+			continue
+		}
+		matches = append(matches, pc)
+		s.relabelConfigs = append(s.relabelConfigs, pc.MetricRelabelConfigs...)
+		s.systemLabels = append(s.systemLabels, pc.MetadataSystemLabels...)
+		s.userLabels = append(s.userLabels, pc.MetadataUserLabels...)
+	}
+	slices.Sort(s.systemLabels)
+	slices.Sort(s.userLabels)
+	s.systemLabels = slices.Compact(s.systemLabels)
+	s.userLabels = slices.Compact(s.userLabels)
+
+	// Of equally long prefixes, the first in file order wins.
+	slices.SortStableFunc(matches, func(a, b *config.PrefixConfig) int {
+		return cmp.Compare(len(b.Prefix), len(a.Prefix))
+	})
+	for _, pc := range matches {
+		if s.maxSeries == 0 {
+			s.maxSeries = pc.MaxSeries
+		}
+		if s.deltaMode == "" {
+			s.deltaMode = pc.DeltaMode
+		}
+		if len(s.gaugePolicies) == 0 {
+			s.gaugePolicies = pc.GaugePolicies
+		}
+		if s.bucketLayout == nil && (len(pc.HistogramBounds) > 0 || pc.HistogramMaxBuckets > 0) {
+			s.bucketLayout = &BucketLayout{Bounds: pc.HistogramBounds, MaxBuckets: pc.HistogramMaxBuckets}
+		}
+		if len(s.summaryQuantiles) == 0 {
+			s.summaryQuantiles = pc.SummaryQuantiles
+		}
+	}
+	if s.maxSeries == 0 {
+		s.maxSeries = c.maxSeriesPerMetric
+	}
+	if s.deltaMode == "" {
+		s.deltaMode = config.DeltaModeGauge
+		if c.aggregateDeltas {
+			s.deltaMode = config.DeltaModeAggregate
+		}
+	}
+
+	actual, _ := c.settings.LoadOrStore(metricType, s)
+	return actual.(*metricSettings)
+}
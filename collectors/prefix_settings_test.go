@@ -0,0 +1,88 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+	"github.com/prometheus-community/stackdriver_exporter/relabel"
+)
+
+func TestSettingsFor(t *testing.T) {
+	t.Parallel()
+
+	dropZone := &relabel.Config{Action: relabel.LabelDrop, Regex: relabel.MustNewRegexp("zone")}
+	dropID := &relabel.Config{Action: relabel.LabelDrop, Regex: relabel.MustNewRegexp("instance_id")}
+	c, err := NewMonitoringCollector("p", nil, MonitoringCollectorOptions{
+		AggregateDeltas:    true,
+		MaxSeriesPerMetric: 100,
+		PrefixConfigs: []config.PrefixConfig{
+			{
+				Prefix:               "custom.googleapis.com/",
+				MetricRelabelConfigs: []*relabel.Config{dropZone},
+				MetadataUserLabels:   []string{"team"},
+				MaxSeries:            10,
+				DeltaMode:            config.DeltaModeRate,
+				HistogramMaxBuckets:  4,
+				SummaryQuantiles:     []float64{0.5},
+			},
+			{
+				Prefix:               "custom.googleapis.com/noisy/",
+				MetricRelabelConfigs: []*relabel.Config{dropID},
+				MetadataUserLabels:   []string{"owner", "team"},
+				MaxSeries:            1,
+				DeltaMode:            config.DeltaModeGauge,
+			},
+			{Prefix: "custom.googleapis.com/noisy/", MaxSeries: 2},
+		},
+	}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]*metricSettings{
+		"compute.googleapis.com/instance/uptime": {
+			maxSeries: 100,
+			deltaMode: config.DeltaModeAggregate,
+		},
+		"custom.googleapis.com/requests": {
+			relabelConfigs:   []*relabel.Config{dropZone},
+			userLabels:       []string{"team"},
+			maxSeries:        10,
+			deltaMode:        config.DeltaModeRate,
+			bucketLayout:     &BucketLayout{MaxBuckets: 4},
+			summaryQuantiles: []float64{0.5},
+		},
+		"custom.googleapis.com/noisy/queue_length": {
+			relabelConfigs:   []*relabel.Config{dropZone, dropID},
+			userLabels:       []string{"owner", "team"},
+			maxSeries:        1,
+			deltaMode:        config.DeltaModeGauge,
+			bucketLayout:     &BucketLayout{MaxBuckets: 4},
+			summaryQuantiles: []float64{0.5},
+		},
+	}
+	for metricType, want := range tests {
+		got := c.settingsFor(metricType)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("settingsFor(%q) = %+v, want %+v", metricType, got, want)
+		}
+		if again := c.settingsFor(metricType); again != got {
+			t.Errorf("settingsFor(%q) resolved the settings twice", metricType)
+		}
+	}
+}
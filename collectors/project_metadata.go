@@ -0,0 +1,268 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	cloudresourcemanagerv3 "google.golang.org/api/cloudresourcemanager/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+// projectMetadataRetry is how long a failed project lookup is remembered
+// before it is attempted again.
+const projectMetadataRetry = time.Minute
+
+// projectMetadata is the Resource Manager metadata of a project.
+type projectMetadata struct {
+	projectID   string
+	number      string
+	displayName string
+	labels      map[string]string
+	// folderPath holds the display names of the parent folders from the top, joined with "/".
+	folderPath string
+}
+
+// resourceManagerGetter fetches single projects and folders by resource name.
+type resourceManagerGetter interface {
+	getProject(ctx context.Context, name string) (*cloudresourcemanagerv3.Project, error)
+	getFolder(ctx context.Context, name string) (*cloudresourcemanagerv3.Folder, error)
+}
+
+func (l *resourceManagerLister) getProject(ctx context.Context, name string) (*cloudresourcemanagerv3.Project, error) {
+	return l.service.Projects.Get(name).Context(ctx).Do()
+}
+
+func (l *resourceManagerLister) getFolder(ctx context.Context, name string) (*cloudresourcemanagerv3.Folder, error) {
+	return l.service.Folders.Get(name).Context(ctx).Do()
+}
+
+type projectMetadataEntry struct {
+	metadata *projectMetadata
+	expiry   time.Time
+}
+
+type folderEntry struct {
+	displayName string
+	parent      string
+	expiry      time.Time
+}
+
+// projectMetadataCache maps project IDs and numbers to their metadata.
+// Concurrent lookups of a project share a single Resource Manager call.
+type projectMetadataCache struct {
+	getter  resourceManagerGetter
+	ttl     time.Duration
+	timeout time.Duration
+	logger  *slog.Logger
+	group   singleflight.Group
+
+	lock     sync.Mutex
+	projects map[string]*projectMetadataEntry
+	folders  map[string]*folderEntry
+}
+
+func newProjectMetadataCache(getter resourceManagerGetter, ttl, timeout time.Duration, logger *slog.Logger) *projectMetadataCache {
+	return &projectMetadataCache{
+		getter:   getter,
+		ttl:      ttl,
+		timeout:  timeout,
+		logger:   logger,
+		projects: make(map[string]*projectMetadataEntry),
+		folders:  make(map[string]*folderEntry),
+	}
+}
+
+// lookup returns the metadata of a project given its ID or number, or nil if
+// it cannot be fetched. Only the first lookup of a project waits for Resource
+// Manager: expired metadata, or none after a failed call, is returned while it
+// is refreshed in the background.
+func (c *projectMetadataCache) lookup(idOrNumber string) *projectMetadata {
+	c.lock.Lock()
+	entry, ok := c.projects[idOrNumber]
+	c.lock.Unlock()
+	if !ok {
+		metadata, _, _ := c.group.Do(idOrNumber, func() (interface{}, error) {
+			return c.refresh(idOrNumber), nil
+		})
+		return metadata.(*projectMetadata)
+	}
+	if time.Now().After(entry.expiry) {
+		c.group.DoChan(idOrNumber, func() (interface{}, error) {
+			return c.refresh(idOrNumber), nil
+		})
+	}
+	return entry.metadata
+}
+
+// refresh fetches the metadata of a project and caches it under its ID and
+// number. On error the cached metadata, if any, is kept and retried later.
+func (c *projectMetadataCache) refresh(idOrNumber string) *projectMetadata {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	metadata, err := c.fetch(ctx, idOrNumber)
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err != nil {
+		c.logger.Debug("error fetching project metadata", "project", idOrNumber, "err", err)
+		var cached *projectMetadata
+		if entry, ok := c.projects[idOrNumber]; ok {
+			cached = entry.metadata
+		}
+		c.projects[idOrNumber] = &projectMetadataEntry{metadata: cached, expiry: now.Add(min(projectMetadataRetry, c.ttl))}
+		return cached
+	}
+
+	entry := &projectMetadataEntry{metadata: metadata, expiry: now.Add(c.ttl)}
+	c.projects[metadata.projectID] = entry
+	c.projects[metadata.number] = entry
+	return metadata
+}
+
+func (c *projectMetadataCache) fetch(ctx context.Context, idOrNumber string) (*projectMetadata, error) {
+	project, err := c.getter.getProject(ctx, "projects/"+idOrNumber)
+	if err != nil {
+		return nil, err
+	}
+	folderPath, err := c.folderPath(ctx, project.Parent)
+	if err != nil {
+		return nil, err
+	}
+	return &projectMetadata{
+		projectID:   project.ProjectId,
+		number:      strings.TrimPrefix(project.Name, "projects/"),
+		displayName: project.DisplayName,
+		labels:      project.Labels,
+		folderPath:  folderPath,
+	}, nil
+}
+
+// folderPath walks up the folders above parent until the organization.
+func (c *projectMetadataCache) folderPath(ctx context.Context, parent string) (string, error) {
+	var names []string
+	for strings.HasPrefix(parent, "folders/") {
+		now := time.Now()
+		c.lock.Lock()
+		folder, ok := c.folders[parent]
+		c.lock.Unlock()
+		if !ok || now.After(folder.expiry) {
+			f, err := c.getter.getFolder(ctx, parent)
+			if err != nil {
+				return "", err
+			}
+			folder = &folderEntry{displayName: f.DisplayName, parent: f.Parent, expiry: now.Add(c.ttl)}
+			c.lock.Lock()
+			c.folders[parent] = folder
+			c.lock.Unlock()
+		}
+		names = append(names, folder.displayName)
+		parent = folder.parent
+	}
+	slices.Reverse(names)
+	return strings.Join(names, "/"), nil
+}
+
+// ProjectEnricher adds project metadata labels to every series and fills
+// project_id from the collector when the monitored resource has none.
+type ProjectEnricher struct {
+	cache  *projectMetadataCache
+	fields []string
+	labels []string
+}
+
+// NewProjectEnricher returns an enricher fetching project metadata through
+// Resource Manager, or nil if enrichment is disabled in cfg.
+func NewProjectEnricher(ctx context.Context, logger *slog.Logger, cfg *config.Config) (*ProjectEnricher, error) {
+	if !cfg.ProjectEnrichment {
+		return nil, nil
+	}
+	service, err := cloudresourcemanagerv3.NewService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newProjectEnricher(&resourceManagerLister{service: service}, logger, cfg), nil
+}
+
+func newProjectEnricher(getter resourceManagerGetter, logger *slog.Logger, cfg *config.Config) *ProjectEnricher {
+	return &ProjectEnricher{
+		cache:  newProjectMetadataCache(getter, cfg.ProjectEnrichmentTTL, cfg.HTTPTimeout, logger),
+		fields: cfg.ProjectEnrichmentFields,
+		labels: cfg.ProjectEnrichmentLabels,
+	}
+}
+
+// enrich fills or normalizes project_id and appends the selected metadata
+// labels. Every series gets the same label keys, with empty values for
+// projects whose metadata is unknown.
+func (e *ProjectEnricher) enrich(collectorTarget string, labelKeys, labelValues []string) ([]string, []string) {
+	idx := slices.Index(labelKeys, "project_id")
+	if idx < 0 && !IsParentTarget(collectorTarget) {
+		labelKeys = append(labelKeys, "project_id")
+		labelValues = append(labelValues, collectorTarget)
+		idx = len(labelKeys) - 1
+	}
+
+	var metadata *projectMetadata
+	if idx >= 0 && labelValues[idx] != "" {
+		metadata = e.cache.lookup(labelValues[idx])
+		if metadata != nil && isProjectNumber(labelValues[idx]) {
+			labelValues[idx] = metadata.projectID
+		}
+	}
+
+	for _, field := range e.fields {
+		var value string
+		if metadata != nil {
+			switch field {
+			case config.ProjectFieldDisplayName:
+				value = metadata.displayName
+			case config.ProjectFieldNumber:
+				value = metadata.number
+			case config.ProjectFieldFolderPath:
+				value = metadata.folderPath
+			}
+		}
+		labelKeys, labelValues = appendLabelIfMissing(labelKeys, labelValues, "project_"+field, value)
+	}
+	for _, key := range e.labels {
+		var value string
+		if metadata != nil {
+			value = metadata.labels[key]
+		}
+		labelKeys, labelValues = appendLabelIfMissing(labelKeys, labelValues, "project_label_"+sanitizeLabelName(key), value)
+	}
+	return labelKeys, labelValues
+}
+
+func appendLabelIfMissing(labelKeys, labelValues []string, key, value string) ([]string, []string) {
+	if slices.Contains(labelKeys, key) {
+		return labelKeys, labelValues
+	}
+	return append(labelKeys, key), append(labelValues, value)
+}
+
+func isProjectNumber(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
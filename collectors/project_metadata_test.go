@@ -0,0 +1,255 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	cloudresourcemanagerv3 "google.golang.org/api/cloudresourcemanager/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+type fakeResourceManager struct {
+	mu            sync.Mutex
+	projectsByKey map[string]*cloudresourcemanagerv3.Project
+	folders       map[string]*cloudresourcemanagerv3.Folder
+	projectCalls  int
+	// release, if set, holds project calls until it is closed.
+	release chan struct{}
+	fail    bool
+}
+
+func (f *fakeResourceManager) getProject(_ context.Context, name string) (*cloudresourcemanagerv3.Project, error) {
+	if f.release != nil {
+		<-f.release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.projectCalls++
+	if f.fail {
+		return nil, errors.New("unavailable")
+	}
+	if p, ok := f.projectsByKey[name]; ok {
+		return p, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeResourceManager) getFolder(_ context.Context, name string) (*cloudresourcemanagerv3.Folder, error) {
+	if folder, ok := f.folders[name]; ok {
+		return folder, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func newFakeResourceManager() *fakeResourceManager {
+	shop := &cloudresourcemanagerv3.Project{
+		Name:        "projects/1234",
+		ProjectId:   "shop-prod",
+		DisplayName: "Shop",
+		Parent:      "folders/2",
+		Labels:      map[string]string{"team": "payments"},
+	}
+	return &fakeResourceManager{
+		projectsByKey: map[string]*cloudresourcemanagerv3.Project{
+			"projects/1234":      shop,
+			"projects/shop-prod": shop,
+		},
+		folders: map[string]*cloudresourcemanagerv3.Folder{
+			"folders/2": {Name: "folders/2", DisplayName: "prod", Parent: "folders/1"},
+			"folders/1": {Name: "folders/1", DisplayName: "retail", Parent: "organizations/9"},
+		},
+	}
+}
+
+func TestProjectEnricherEnrich(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		ProjectEnrichmentFields: []string{config.ProjectFieldDisplayName, config.ProjectFieldNumber, config.ProjectFieldFolderPath},
+		ProjectEnrichmentLabels: []string{"team"},
+		ProjectEnrichmentTTL:    time.Hour,
+		HTTPTimeout:             time.Second,
+	}
+	wantKeys := []string{"zone", "project_id", "project_display_name", "project_number", "project_folder_path", "project_label_team"}
+
+	tests := []struct {
+		name       string
+		target     string
+		keys       []string
+		values     []string
+		wantKeys   []string
+		wantValues []string
+	}{
+		{
+			name:       "project ID",
+			target:     "other",
+			keys:       []string{"zone", "project_id"},
+			values:     []string{"us-east1-b", "shop-prod"},
+			wantKeys:   wantKeys,
+			wantValues: []string{"us-east1-b", "shop-prod", "Shop", "1234", "retail/prod", "payments"},
+		},
+		{
+			name:       "project number is replaced by the ID",
+			target:     "other",
+			keys:       []string{"zone", "project_id"},
+			values:     []string{"us-east1-b", "1234"},
+			wantKeys:   wantKeys,
+			wantValues: []string{"us-east1-b", "shop-prod", "Shop", "1234", "retail/prod", "payments"},
+		},
+		{
+			name:       "missing project_id is filled from the collector",
+			target:     "shop-prod",
+			keys:       []string{"zone"},
+			values:     []string{"us-east1-b"},
+			wantKeys:   wantKeys,
+			wantValues: []string{"us-east1-b", "shop-prod", "Shop", "1234", "retail/prod", "payments"},
+		},
+		{
+			name:       "unknown project gets empty metadata",
+			target:     "other",
+			keys:       []string{"zone", "project_id"},
+			values:     []string{"us-east1-b", "unknown"},
+			wantKeys:   wantKeys,
+			wantValues: []string{"us-east1-b", "unknown", "", "", "", ""},
+		},
+		{
+			name:       "folder target does not fill project_id",
+			target:     "folders/1",
+			keys:       []string{"zone"},
+			values:     []string{"us-east1-b"},
+			wantKeys:   []string{"zone", "project_display_name", "project_number", "project_folder_path", "project_label_team"},
+			wantValues: []string{"us-east1-b", "", "", "", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			e := newProjectEnricher(newFakeResourceManager(), slog.Default(), cfg)
+			gotKeys, gotValues := e.enrich(tt.target, tt.keys, tt.values)
+			if !reflect.DeepEqual(gotKeys, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", gotKeys, tt.wantKeys)
+			}
+			if !reflect.DeepEqual(gotValues, tt.wantValues) {
+				t.Errorf("values = %v, want %v", gotValues, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestProjectMetadataCacheSharesIDAndNumber(t *testing.T) {
+	t.Parallel()
+
+	rm := newFakeResourceManager()
+	c := newProjectMetadataCache(rm, time.Hour, time.Second, slog.Default())
+
+	if md := c.lookup("1234"); md == nil || md.projectID != "shop-prod" {
+		t.Fatalf("lookup(1234) = %+v, want shop-prod", md)
+	}
+	if md := c.lookup("shop-prod"); md == nil || md.number != "1234" {
+		t.Fatalf("lookup(shop-prod) = %+v, want number 1234", md)
+	}
+	if c.lookup("unknown") != nil || c.lookup("unknown") != nil {
+		t.Fatal("lookup(unknown) should return nil")
+	}
+	if rm.projectCalls != 2 {
+		t.Errorf("made %d project calls, want 2", rm.projectCalls)
+	}
+}
+
+func TestProjectMetadataCacheSharesConcurrentLookups(t *testing.T) {
+	t.Parallel()
+
+	rm := newFakeResourceManager()
+	rm.release = make(chan struct{})
+	c := newProjectMetadataCache(rm, time.Hour, time.Second, slog.Default())
+
+	var wg sync.WaitGroup
+	results := make([]*projectMetadata, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.lookup("shop-prod")
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(rm.release)
+	wg.Wait()
+
+	for i, md := range results {
+		if md == nil || md.projectID != "shop-prod" {
+			t.Errorf("lookup %d = %+v, want shop-prod", i, md)
+		}
+	}
+	if rm.projectCalls != 1 {
+		t.Errorf("made %d project calls, want 1", rm.projectCalls)
+	}
+}
+
+func TestProjectMetadataCacheServesExpiredMetadata(t *testing.T) {
+	t.Parallel()
+
+	rm := newFakeResourceManager()
+	c := newProjectMetadataCache(rm, time.Hour, time.Second, slog.Default())
+	if md := c.lookup("shop-prod"); md == nil {
+		t.Fatal("lookup(shop-prod) = nil")
+	}
+
+	c.lock.Lock()
+	c.projects["shop-prod"].expiry = time.Now().Add(-time.Second)
+	c.lock.Unlock()
+	rm.mu.Lock()
+	rm.fail = true
+	rm.mu.Unlock()
+	rm.release = make(chan struct{})
+
+	// The refresh is held, so the expired metadata must be returned without waiting for it.
+	if md := c.lookup("shop-prod"); md == nil || md.displayName != "Shop" {
+		t.Fatalf("lookup(shop-prod) = %+v, want the expired metadata", md)
+	}
+	close(rm.release)
+
+	// The failed refresh keeps the metadata and retries it later.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.lock.Lock()
+		entry := c.projects["shop-prod"]
+		c.lock.Unlock()
+		if time.Now().Before(entry.expiry) {
+			if entry.metadata == nil || entry.metadata.displayName != "Shop" {
+				t.Fatalf("metadata after failed refresh = %+v, want it kept", entry.metadata)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("metadata was not refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if rm.projectCalls != 2 {
+		t.Errorf("made %d project calls, want 2", rm.projectCalls)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// projectList holds the resolved project IDs shared by a Runtime and the
+// siblings derived from it.
+type projectList struct {
+	lock sync.RWMutex
+	ids  []string
+}
+
+func (l *projectList) get() []string {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return slices.Clone(l.ids)
+}
+
+func (l *projectList) set(ids []string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.ids = slices.Clone(ids)
+}
+
+// projectDiscoveryMetrics reports the outcome of project refreshes.
+type projectDiscoveryMetrics struct {
+	discoveredProjects   prometheus.Gauge
+	refreshErrorsTotal   prometheus.Counter
+	lastRefreshTimestamp prometheus.Gauge
+}
+
+func newProjectDiscoveryMetrics() *projectDiscoveryMetrics {
+	return &projectDiscoveryMetrics{
+		discoveredProjects: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "stackdriver_exporter",
+			Name:      "discovered_projects",
+			Help:      "Number of projects currently scraped.",
+		}),
+		refreshErrorsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "stackdriver_exporter",
+			Name:      "project_refresh_errors_total",
+			Help:      "Total number of failed project refreshes.",
+		}),
+		lastRefreshTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "stackdriver_exporter",
+			Name:      "project_refresh_last_success_timestamp",
+			Help:      "Number of seconds since 1970 since the last successful project refresh.",
+		}),
+	}
+}
+
+func (m *projectDiscoveryMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.discoveredProjects.Describe(ch)
+	m.refreshErrorsTotal.Describe(ch)
+	m.lastRefreshTimestamp.Describe(ch)
+}
+
+func (m *projectDiscoveryMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.discoveredProjects.Collect(ch)
+	m.refreshErrorsTotal.Collect(ch)
+	m.lastRefreshTimestamp.Collect(ch)
+}
+
+// ProjectIDs returns the projects currently scraped by the Runtime.
+func (r *Runtime) ProjectIDs() []string {
+	return r.projects.get()
+}
+
+// ProjectDiscoveryCollector returns a collector reporting the number of
+// scraped projects and the outcome of project refreshes.
+func (r *Runtime) ProjectDiscoveryCollector() prometheus.Collector {
+	return r.discovery
+}
+
+// RefreshProjects resolves the configured projects again. On error the
+// previous projects are kept.
+func (r *Runtime) RefreshProjects(ctx context.Context) error {
+	projectIDs, err := r.resolveProjects(ctx)
+	if err != nil {
+		r.discovery.refreshErrorsTotal.Inc()
+		return err
+	}
+
+	previous := r.projects.get()
+	for _, id := range projectIDs {
+		if !slices.Contains(previous, id) {
+			r.logger.Info("discovered project", "project_id", id)
+		}
+	}
+	for _, id := range previous {
+		if !slices.Contains(projectIDs, id) {
+			r.logger.Info("project no longer discovered", "project_id", id)
+			r.releaseProject(id)
+		}
+	}
+
+	r.projects.set(projectIDs)
+	if r.projectOwnership != nil {
+		r.projectOwnership.setConfigured(projectIDs)
+	}
+	r.tracked.retainTargets(r.targets())
+	r.discovery.discoveredProjects.Set(float64(len(projectIDs)))
+	r.discovery.lastRefreshTimestamp.SetToCurrentTime()
+	return nil
+}
+
+// releaseProject drops the state kept for a project that is no longer
+// discovered: its aggregated delta series, its cached metric descriptors and
+// the monitored projects its collector owned.
+func (r *Runtime) releaseProject(projectID string) {
+	prefix := targetResource(projectID) + "/metricDescriptors/"
+	for _, store := range []any{r.counterStore, r.histogramStore} {
+		if forgetter, ok := store.(DeltaDescriptorForgetter); ok {
+			forgetter.ForgetDescriptors(prefix)
+		}
+	}
+	if r.descriptorCache != nil {
+		r.descriptorCache.forgetProject(projectID)
+	}
+	if r.projectOwnership != nil {
+		r.projectOwnership.forgetCollector(projectID)
+	}
+}
+
+// RunProjectRefresh refreshes the projects every interval until ctx is done.
+func (r *Runtime) RunProjectRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.RefreshProjects(ctx); err != nil {
+				r.logger.Error("failed to refresh projects", "err", err)
+			}
+		}
+	}
+}
+
+// ProjectsCollector collects the MonitoringCollectors of the Runtime's current
+// projects. Collectors of projects that are still present are reused across
+// project refreshes, so their delta stores are kept.
+type ProjectsCollector struct {
+	runtime *Runtime
+
+	lock       sync.Mutex
+	collectors map[string]*MonitoringCollector
+}
+
+// ProjectsCollector returns a collector following the Runtime's projects
+// across refreshes, scoped to all configured prefixes.
+func (r *Runtime) ProjectsCollector() *ProjectsCollector {
+	return &ProjectsCollector{runtime: r, collectors: make(map[string]*MonitoringCollector)}
+}
+
+// Describe sends no descriptors: the set of projects, and with it the
+// project_id label of the exporter metrics, changes at runtime.
+func (p *ProjectsCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+func (p *ProjectsCollector) Collect(ch chan<- prometheus.Metric) {
+	wg := &sync.WaitGroup{}
+	for _, c := range p.current() {
+		wg.Add(1)
+		go func(c *MonitoringCollector) {
+			defer wg.Done()
+			c.Collect(ch)
+		}(c)
+	}
+	wg.Wait()
+}
+
+// current returns the collectors of the current projects, building the ones
+// of newly discovered projects and forgetting the removed ones.
+func (p *ProjectsCollector) current() []*MonitoringCollector {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	targets := p.runtime.targets()
+	next := make(map[string]*MonitoringCollector, len(targets))
+	out := make([]*MonitoringCollector, 0, len(targets))
+	for _, projectID := range targets {
+		c, ok := p.collectors[projectID]
+		if !ok {
+			var err error
+			c, err = p.runtime.collectorFor(projectID, nil)
+			if err != nil {
+				p.runtime.logger.Error("error creating monitoring collector", "project_id", projectID, "err", err)
+				continue
+			}
+		} else {
+			p.runtime.track(collectorCacheKey(projectID, nil), c)
+		}
+		next[projectID] = c
+		out = append(out, c)
+	}
+	p.collectors = next
+	return out
+}
@@ -0,0 +1,206 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+func newTestRuntime(resolve func(ctx context.Context) ([]string, error)) *Runtime {
+	return &Runtime{
+		cfg:             &config.Config{MetricsPrefixes: []string{"compute.googleapis.com/"}},
+		projects:        &projectList{},
+		resolveProjects: resolve,
+		discovery:       newProjectDiscoveryMetrics(),
+		logger:          slog.Default(),
+		counterStore:    nopCounterStore{},
+		histogramStore:  nopHistogramStore{},
+		tracked:         newTrackedCollectors(time.Hour),
+	}
+}
+
+func TestRefreshProjects(t *testing.T) {
+	t.Parallel()
+
+	var (
+		projects []string
+		err      error
+	)
+	r := newTestRuntime(func(context.Context) ([]string, error) { return projects, err })
+
+	projects = []string{"project-a", "project-b"}
+	if err := r.RefreshProjects(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.ProjectIDs(); !slices.Equal(got, projects) {
+		t.Errorf("ProjectIDs() = %v, want %v", got, projects)
+	}
+	if got := testutil.ToFloat64(r.discovery.discoveredProjects); got != 2 {
+		t.Errorf("discovered_projects = %v, want 2", got)
+	}
+
+	projects, err = nil, errors.New("permission denied")
+	if r.RefreshProjects(context.Background()) == nil {
+		t.Fatal("expected refresh error, got nil")
+	}
+	if got := r.ProjectIDs(); !slices.Equal(got, []string{"project-a", "project-b"}) {
+		t.Errorf("ProjectIDs() after failed refresh = %v, want previous projects", got)
+	}
+	if got := testutil.ToFloat64(r.discovery.refreshErrorsTotal); got != 1 {
+		t.Errorf("project_refresh_errors_total = %v, want 1", got)
+	}
+}
+
+func TestProjectsCollectorKeepsCollectorsOfRemainingProjects(t *testing.T) {
+	t.Parallel()
+
+	var projects []string
+	r := newTestRuntime(func(context.Context) ([]string, error) { return projects, nil })
+	p := r.ProjectsCollector()
+
+	projects = []string{"project-a", "project-b"}
+	if err := r.RefreshProjects(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	before := p.current()
+	if len(before) != 2 {
+		t.Fatalf("got %d collectors, want 2", len(before))
+	}
+
+	projects = []string{"project-b", "project-c"}
+	if err := r.RefreshProjects(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	after := p.current()
+	if len(after) != 2 {
+		t.Fatalf("got %d collectors, want 2", len(after))
+	}
+	if after[0] != before[1] {
+		t.Error("collector of project-b was rebuilt, want it reused")
+	}
+	if after[1].projectID != "project-c" {
+		t.Errorf("second collector is for %q, want project-c", after[1].projectID)
+	}
+}
+
+func TestProjectsCollectorKeepsCollectorsTracked(t *testing.T) {
+	t.Parallel()
+
+	const ttl = 50 * time.Millisecond
+	var projects []string
+	r := newTestRuntime(func(context.Context) ([]string, error) { return projects, nil })
+	r.cfg.CardinalityStats = true
+	r.tracked = newTrackedCollectors(ttl)
+	r.targetParents = []string{"folders/123"}
+	r.descriptorProjectID = "project-a"
+	p := r.ProjectsCollector()
+
+	scrape := func() {
+		for _, c := range p.current() {
+			c.lastCardinalityStats = &CardinalityStats{ProjectID: c.projectID}
+		}
+	}
+
+	projects = []string{"project-a"}
+	if err := r.RefreshProjects(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		scrape()
+		time.Sleep(ttl / 2)
+	}
+	projects = []string{"project-a", "project-b"}
+	if err := r.RefreshProjects(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	scrape()
+
+	var got []string
+	for _, stats := range r.CardinalityStats() {
+		got = append(got, stats.ProjectID)
+	}
+	slices.Sort(got)
+	want := []string{"folders/123", "project-a", "project-b"}
+	if !slices.Equal(got, want) {
+		t.Errorf("CardinalityStats() lists %v, want %v", got, want)
+	}
+}
+
+// forgettingCounterStore records the descriptor prefixes it was asked to forget.
+type forgettingCounterStore struct {
+	nopCounterStore
+	forgotten []string
+}
+
+func (s *forgettingCounterStore) ForgetDescriptors(prefix string) {
+	s.forgotten = append(s.forgotten, prefix)
+}
+
+func TestRefreshProjectsReleasesRemovedProjects(t *testing.T) {
+	t.Parallel()
+
+	var projects []string
+	r := newTestRuntime(func(context.Context) ([]string, error) { return projects, nil })
+	store := &forgettingCounterStore{}
+	r.counterStore = store
+	lister := &fakeDescriptorLister{}
+	lister.returns(makeDummyMetrics(1), nil, nil)
+	r.descriptorCache = newSharedDescriptorCache(slog.Default(), time.Hour, time.Minute, false, lister.list)
+	r.projectOwnership = newProjectOwnership(nil, time.Hour)
+
+	projects = []string{"project-a", "project-b"}
+	if err := r.RefreshProjects(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	listings := []descriptorListing{
+		{project: "project-a", prefix: "custom.googleapis.com/"},
+		{project: "project-b", prefix: "custom.googleapis.com/"},
+		{project: "project-b", prefix: "compute.googleapis.com/", monitoredProject: "project-a"},
+	}
+	for _, listing := range listings {
+		if _, err := r.descriptorCache.get(listing, func() {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scrape := time.Now()
+	r.projectOwnership.Owns("project-a", "shared", scrape)
+	r.projectOwnership.Owns("project-b", "shared", scrape)
+
+	projects = []string{"project-b"}
+	if err := r.RefreshProjects(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"projects/project-a/metricDescriptors/"}; !slices.Equal(store.forgotten, want) {
+		t.Errorf("forgotten delta descriptors = %v, want %v", store.forgotten, want)
+	}
+	for _, listing := range listings {
+		_, cached := r.descriptorCache.entries[listing.cacheKey()]
+		if want := listing == listings[1]; cached != want {
+			t.Errorf("descriptors of %+v cached = %v, want %v", listing, cached, want)
+		}
+	}
+	if !r.projectOwnership.Owns("project-b", "shared", time.Now()) {
+		t.Error("project owned by the removed collector was not handed over")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/compute/v1"
@@ -37,26 +38,91 @@ type HistogramStoreFactory func(logger *slog.Logger, ttl time.Duration) DeltaHis
 
 // Runtime holds the resolved state produced by NewRuntime.
 type Runtime struct {
-	cfg                   *config.Config
-	projectIDs            []string
-	service               *monitoring.Service
-	logger                *slog.Logger
-	counterStoreFactory   CounterStoreFactory
-	histogramStoreFactory HistogramStoreFactory
-	cache                 *collectorCache
+	cfg                 *config.Config
+	projects            *projectList
+	resolveProjects     func(ctx context.Context) ([]string, error)
+	discovery           *projectDiscoveryMetrics
+	service             *monitoring.Service
+	logger              *slog.Logger
+	counterStore        DeltaCounterStore
+	histogramStore      DeltaHistogramStore
+	cache               *collectorCache
+	tracked             *trackedCollectors
+	projectOwnership    *projectOwnership
+	scopingProjectID    string
+	metricsScope        *metricsScope
+	targetParents       []string
+	descriptorProjectID string
+	projectEnricher     *ProjectEnricher
+	descriptorCache     *sharedDescriptorCache
 }
 
 // NewRuntime resolves project IDs and creates the monitoring service. The
 // caller must have run cfg.Validate first.
 //
-// counterFactory and histogramFactory are invoked each time a new collector
-// is built. The returned Runtime does not cache collectors; call WithCache
-// to derive a sibling that does.
+// counterFactory and histogramFactory are invoked once. The stores they
+// return are owned by the Runtime and shared by every collector it builds,
+// including those of its WithCache siblings, so rebuilt and prefix-filtered
+// collectors keep adding to the same counters. Collectors name the
+// descriptors they store deltas under after their target, see
+// deltaDescriptor, so the series of different targets stay apart and expire
+// with the stores' own TTL. The returned Runtime does not
+// cache collectors; call WithCache to derive a sibling that does.
 func NewRuntime(ctx context.Context, logger *slog.Logger, cfg *config.Config, counterFactory CounterStoreFactory, histogramFactory HistogramStoreFactory) (*Runtime, error) {
 	if !cfg.Validated() {
 		return nil, fmt.Errorf("config has not been validated; call cfg.Validate before NewRuntime")
 	}
 
+	service, err := createMonitoringService(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := newMetricsScope()
+	r := &Runtime{
+		cfg:      cfg,
+		projects: &projectList{},
+		resolveProjects: func(ctx context.Context) ([]string, error) {
+			return resolveProjectIDs(ctx, logger, cfg, scope)
+		},
+		discovery:        newProjectDiscoveryMetrics(),
+		service:          service,
+		logger:           logger,
+		counterStore:     counterFactory(logger, cfg.AggregateDeltasTTL),
+		histogramStore:   histogramFactory(logger, cfg.AggregateDeltasTTL),
+		tracked:          newTrackedCollectors(collectorCacheTTL(cfg)),
+		scopingProjectID: cfg.ScopingProjectID,
+		metricsScope:     scope,
+		descriptorCache:  newSharedDescriptorCache(logger, cfg.DescriptorCacheTTL, cfg.HTTPTimeout, cfg.DescriptorCacheOnlyGoogle, newDescriptorLister(service, descriptorSelector(cfg), logger)),
+	}
+	if cfg.DeduplicateProjects {
+		r.projectOwnership = newProjectOwnership(nil, cfg.OwnershipClaimTTL)
+	}
+	if len(cfg.TargetParents) > 0 {
+		r.targetParents = deduplicateProjectIDs(cfg.TargetParents)
+		r.descriptorProjectID = cfg.TargetDescriptorProjectID
+		if r.descriptorProjectID == "" {
+			if r.descriptorProjectID, err = discoverDefaultProjectID(ctx); err != nil {
+				return nil, fmt.Errorf("failed to discover descriptor project for target parents: %w", err)
+			}
+		}
+	}
+	if r.projectEnricher, err = NewProjectEnricher(ctx, logger, cfg); err != nil {
+		return nil, fmt.Errorf("failed to create project enricher: %w", err)
+	}
+	if err := r.RefreshProjects(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// resolveProjectIDs returns the projects to scrape: the projects matching
+// the projects filter, the projects below the configured parents and the
+// configured projects, or the default project when there are neither
+// projects nor target parents. In scope
+// mode, projects of the metrics scope are replaced by the scoping project,
+// and scope is updated with them.
+func resolveProjectIDs(ctx context.Context, logger *slog.Logger, cfg *config.Config, scope *metricsScope) ([]string, error) {
 	var projectIDs []string
 
 	if cfg.ProjectsFilter != "" {
@@ -67,9 +133,17 @@ func NewRuntime(ctx context.Context, logger *slog.Logger, cfg *config.Config, co
 		projectIDs = append(projectIDs, ids...)
 	}
 
+	if len(cfg.ProjectsParents) > 0 {
+		ids, err := getProjectIDsFromParents(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve project IDs from projects_parents: %w", err)
+		}
+		projectIDs = append(projectIDs, ids...)
+	}
+
 	projectIDs = append(projectIDs, cfg.ProjectIDs...)
 
-	if len(projectIDs) == 0 {
+	if len(projectIDs) == 0 && len(cfg.TargetParents) == 0 {
 		id, err := discoverDefaultProjectID(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to discover default GCP project: %w", err)
@@ -79,26 +153,22 @@ func NewRuntime(ctx context.Context, logger *slog.Logger, cfg *config.Config, co
 
 	projectIDs = deduplicateProjectIDs(projectIDs)
 
-	service, err := createMonitoringService(ctx, cfg)
-	if err != nil {
-		return nil, err
+	if cfg.ScopingProjectID != "" {
+		members, err := scope.refresh(ctx, cfg, cfg.ScopingProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve metrics scope: %w", err)
+		}
+		projectIDs = splitScopedProjects(cfg.ScopingProjectID, projectIDs, members)
+		logger.Debug("resolved metrics scope", "scoping_project_id", cfg.ScopingProjectID, "scope_projects", len(members), "unscoped_projects", len(projectIDs)-1)
 	}
-
-	return &Runtime{
-		cfg:                   cfg,
-		projectIDs:            projectIDs,
-		service:               service,
-		logger:                logger,
-		counterStoreFactory:   counterFactory,
-		histogramStoreFactory: histogramFactory,
-	}, nil
+	return projectIDs, nil
 }
 
 // WithCache returns a Runtime configured to cache its collectors per
 // (project, prefix-filter). Subsequent calls to Collectors or
-// CollectorsForPrefixes reuse cached entries until they expire, which lets
-// delta-counter state survive repeated rebuilds. The TTL is derived from
-// AggregateDeltasTTL and DescriptorCacheTTL.
+// CollectorsForPrefixes reuse cached entries until they expire. Delta-counter
+// state is owned by the Runtime, so it survives expiry. The TTL is derived
+// from AggregateDeltasTTL and DescriptorCacheTTL.
 //
 // HTTP scrape paths that rebuild collectors per request (?collect= filtering)
 // want this; embedded callers that hold a long-lived registry do not.
@@ -125,8 +195,9 @@ func (r *Runtime) CollectorsForPrefixes(prefixFilter []string) ([]*MonitoringCol
 }
 
 func (r *Runtime) buildCollectors(prefixFilter []string) ([]*MonitoringCollector, error) {
-	result := make([]*MonitoringCollector, 0, len(r.projectIDs))
-	for _, projectID := range r.projectIDs {
+	targets := r.targets()
+	result := make([]*MonitoringCollector, 0, len(targets))
+	for _, projectID := range targets {
 		c, err := r.collectorFor(projectID, prefixFilter)
 		if err != nil {
 			return nil, fmt.Errorf("collector for %q: %w", projectID, err)
@@ -137,33 +208,74 @@ func (r *Runtime) buildCollectors(prefixFilter []string) ([]*MonitoringCollector
 }
 
 func (r *Runtime) collectorFor(projectID string, prefixFilter []string) (*MonitoringCollector, error) {
-	if r.cache == nil {
-		return r.newCollector(projectID, prefixFilter)
-	}
 	key := collectorCacheKey(projectID, prefixFilter)
-	if c, ok := r.cache.Get(key); ok {
-		return c, nil
+	if r.cache != nil {
+		if c, ok := r.cache.Get(key); ok {
+			r.track(key, c)
+			return c, nil
+		}
 	}
 	c, err := r.newCollector(projectID, prefixFilter)
 	if err != nil {
 		return nil, err
 	}
-	r.cache.Store(key, c)
+	if r.cache != nil {
+		r.cache.Store(key, c)
+	}
+	r.track(key, c)
 	return c, nil
 }
 
+// track records c as used for its cardinality statistics, if they are
+// enabled.
+func (r *Runtime) track(key string, c *MonitoringCollector) {
+	if r.cfg.CardinalityStats {
+		r.tracked.Store(key, c)
+	}
+}
+
+// CardinalityStats returns the statistics of the last scrape of every
+// collector built for a distinct (project, prefix-filter) pair, ordered by
+// project. Pairs no collector was requested for in the collector cache TTL
+// are left out. It is empty unless CardinalityStats is enabled in the config.
+func (r *Runtime) CardinalityStats() []*CardinalityStats {
+	return r.tracked.CardinalityStats()
+}
+
+// targets returns the resolved projects followed by the folders and
+// organizations collectors are built for.
+func (r *Runtime) targets() []string {
+	return append(r.ProjectIDs(), r.targetParents...)
+}
+
 func (r *Runtime) newCollector(projectID string, prefixFilter []string) (*MonitoringCollector, error) {
 	filtered := r.filterMetricTypePrefixes(prefixFilter)
+	opts := monitoringCollectorOptionsForPrefixes(r.cfg, filtered)
+	if r.projectOwnership != nil {
+		opts.ProjectOwner = r.projectOwnership
+	}
+	opts.ScopeMetrics = r.scopingProjectID != "" && projectID == r.scopingProjectID
+	opts.metricsScope = r.metricsScope
+	opts.DescriptorProjectID = r.descriptorProjectID
+	opts.ProjectEnricher = r.projectEnricher
+	opts.sharedDescriptors = r.descriptorCache
 	return NewMonitoringCollector(
 		projectID,
 		r.service,
-		monitoringCollectorOptionsForPrefixes(r.cfg, filtered),
+		opts,
 		r.logger,
-		r.counterStoreFactory(r.logger, r.cfg.AggregateDeltasTTL),
-		r.histogramStoreFactory(r.logger, r.cfg.AggregateDeltasTTL),
+		r.counterStore,
+		r.histogramStore,
 	)
 }
 
+// DescriptorCacheCollector returns a collector reporting the hits, misses and
+// background refreshes of the metric descriptor cache shared by the collectors
+// of the Runtime.
+func (r *Runtime) DescriptorCacheCollector() prometheus.Collector {
+	return r.descriptorCache
+}
+
 // filterMetricTypePrefixes resolves a request-time prefix filter against the
 // configured prefixes. nil/empty means "use everything configured"; otherwise
 // only the request prefixes whose configured parent matches are kept.
@@ -21,6 +21,8 @@ import (
 	"testing"
 	"time"
 
+	"google.golang.org/api/monitoring/v3"
+
 	"github.com/prometheus-community/stackdriver_exporter/config"
 )
 
@@ -189,3 +191,36 @@ func TestRuntimeFilterMetricTypePrefixes(t *testing.T) {
 		})
 	}
 }
+
+func TestRuntimeSharesDeltaStores(t *testing.T) {
+	t.Parallel()
+
+	store := &summingCounterStore{}
+	r := newTestRuntime(func(context.Context) ([]string, error) { return []string{"p"}, nil })
+	r.counterStore = store
+
+	unfiltered, err := r.newCollector("p", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := r.newCollector("p", []string{"compute.googleapis.com/instance/cpu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	descriptor := &monitoring.MetricDescriptor{Name: "projects/p/metricDescriptors/compute.googleapis.com/instance/cpu/usage_time"}
+	now := time.Now()
+	unfiltered.counterStore.Increment(descriptor, &ConstMetric{Value: 1, ReportTime: now})
+	filtered.counterStore.Increment(descriptor, &ConstMetric{Value: 2, ReportTime: now.Add(time.Minute)})
+
+	// A collector built again, as after its cache entry expired, finds the same counters.
+	rebuilt, err := r.newCollector("p", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebuilt.counterStore.Increment(descriptor, &ConstMetric{Value: 3, ReportTime: now.Add(2 * time.Minute)})
+
+	if store.value != 6 {
+		t.Errorf("shared counter = %v, want 6", store.value)
+	}
+}
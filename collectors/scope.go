@@ -0,0 +1,195 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2/google"
+	cloudresourcemanagerv3 "google.golang.org/api/cloudresourcemanager/v3"
+	monitoringv1 "google.golang.org/api/monitoring/v1"
+	"google.golang.org/api/option"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+// scopeSeriesRecorder counts the series exported per monitored project during
+// a single scrape of a scoping project's collector. A nil recorder records
+// nothing.
+type scopeSeriesRecorder struct {
+	mu     sync.Mutex
+	series map[string]int
+}
+
+func newScopeSeriesRecorder() *scopeSeriesRecorder {
+	return &scopeSeriesRecorder{series: make(map[string]int)}
+}
+
+func (r *scopeSeriesRecorder) add(projectID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.series[projectID]++
+}
+
+// reportScopeMetrics updates the per-project self-metrics of a scoping
+// project's collector from the series recorded in the last scrape. Projects
+// that left the metrics scope are dropped from scope_last_seen_timestamp.
+func (c *MonitoringCollector) reportScopeMetrics(r *scopeSeriesRecorder, scrapeTime time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.scopeLastSeenProjectsLock.Lock()
+	defer c.scopeLastSeenProjectsLock.Unlock()
+
+	c.scopeSeriesMetric.Reset()
+	for projectID, series := range r.series {
+		c.scopeSeriesMetric.WithLabelValues(projectID).Set(float64(series))
+		c.scopeLastSeenTimestampMetric.WithLabelValues(projectID).Set(float64(scrapeTime.Unix()))
+		c.scopeLastSeenProjects[projectID] = struct{}{}
+	}
+	for projectID := range c.scopeLastSeenProjects {
+		if projectID != c.projectID && !c.metricsScope.includes(projectID) {
+			c.scopeLastSeenTimestampMetric.DeleteLabelValues(projectID)
+			delete(c.scopeLastSeenProjects, projectID)
+		}
+	}
+}
+
+// splitScopedProjects returns the projects that need a collector of their own
+// when scopingProjectID's collector already returns the series of every
+// project in members. The scoping project comes first.
+func splitScopedProjects(scopingProjectID string, projectIDs, members []string) []string {
+	inScope := make(map[string]struct{}, len(members)+1)
+	inScope[scopingProjectID] = struct{}{}
+	for _, id := range members {
+		inScope[id] = struct{}{}
+	}
+
+	out := []string{scopingProjectID}
+	for _, id := range projectIDs {
+		if _, ok := inScope[id]; !ok {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// metricsScope holds the projects of the scoping project's metrics scope as
+// of the last project refresh.
+type metricsScope struct {
+	lock    sync.Mutex
+	members map[string]struct{}
+	// projectIDs maps the project numbers monitored projects are named by to
+	// their IDs. It is kept across refreshes, so only projects new to the
+	// scope are looked up.
+	projectIDs map[string]string
+}
+
+func newMetricsScope() *metricsScope {
+	return &metricsScope{projectIDs: make(map[string]string)}
+}
+
+// includes reports whether projectID is in the metrics scope. A nil scope, or
+// one not resolved yet, includes every project.
+func (s *metricsScope) includes(projectID string) bool {
+	if s == nil {
+		return true
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.members == nil {
+		return true
+	}
+	_, ok := s.members[projectID]
+	return ok
+}
+
+// refresh returns the IDs of the projects monitored by the metrics scope of
+// scopingProjectID and remembers them.
+func (s *metricsScope) refresh(ctx context.Context, cfg *config.Config, scopingProjectID string) ([]string, error) {
+	googleClient, err := google.DefaultClient(ctx, monitoringv1.MonitoringReadScope)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Google client: %w", err)
+	}
+	googleClient.Timeout = cfg.HTTPTimeout
+
+	service, err := monitoringv1.NewService(ctx, option.WithHTTPClient(googleClient), option.WithUniverseDomain(cfg.UniverseDomain))
+	if err != nil {
+		return nil, fmt.Errorf("error creating Google Cloud Monitoring v1 service: %w", err)
+	}
+	scope, err := service.Locations.Global.MetricsScopes.Get("locations/global/metricsScopes/" + scopingProjectID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting metrics scope of %q: %w", scopingProjectID, err)
+	}
+
+	resourceManager, err := cloudresourcemanagerv3.NewService(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []string
+	for _, monitored := range scope.MonitoredProjects {
+		if monitored.IsTombstoned {
+			continue
+		}
+		numbers = append(numbers, monitored.Name[strings.LastIndex(monitored.Name, "/")+1:])
+	}
+	return s.update(ctx, numbers, func(ctx context.Context, number string) (string, error) {
+		project, err := resourceManager.Projects.Get("projects/" + number).Context(ctx).Do()
+		if err != nil {
+			return "", err
+		}
+		return project.ProjectId, nil
+	})
+}
+
+// update replaces the projects of the metrics scope with the projects
+// numbered numbers, and returns their IDs. Only numbers not seen by a
+// previous update are resolved with getProjectID.
+func (s *metricsScope) update(ctx context.Context, numbers []string, getProjectID func(ctx context.Context, number string) (string, error)) ([]string, error) {
+	s.lock.Lock()
+	known := s.projectIDs
+	s.lock.Unlock()
+
+	// Monitored projects are named by project number, series carry the project ID.
+	projectIDs := make(map[string]string, len(numbers))
+	ids := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		id, ok := known[number]
+		if !ok {
+			var err error
+			if id, err = getProjectID(ctx, number); err != nil {
+				return nil, fmt.Errorf("error resolving monitored project %q: %w", number, err)
+			}
+		}
+		projectIDs[number] = id
+		ids = append(ids, id)
+	}
+
+	members := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		members[id] = struct{}{}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.projectIDs = projectIDs
+	s.members = members
+	return ids, nil
+}
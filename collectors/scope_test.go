@@ -0,0 +1,148 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/api/monitoring/v3"
+)
+
+func TestSplitScopedProjects(t *testing.T) {
+	t.Parallel()
+
+	got := splitScopedProjects("scope", []string{"a", "outside", "scope", "b"}, []string{"a", "b", "c"})
+	want := []string{"scope", "outside"}
+	if !slices.Equal(got, want) {
+		t.Errorf("splitScopedProjects() = %v, want %v", got, want)
+	}
+}
+
+func TestReportTimeSeriesMetricsScopeSeries(t *testing.T) {
+	t.Parallel()
+
+	const metricType = "custom.googleapis.com/requests"
+	c, err := NewMonitoringCollector("scope", nil, MonitoringCollectorOptions{ScopeMetrics: true}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &monitoring.ListTimeSeriesResponse{}
+	for i, projectID := range []string{"a", "a", "b", ""} {
+		ts := gaugeTimeSeries(metricType, i)
+		if projectID != "" {
+			ts.Resource.Labels["project_id"] = projectID
+		}
+		page.TimeSeries = append(page.TimeSeries, ts)
+	}
+	descriptor := &monitoring.MetricDescriptor{Type: metricType, Unit: "1"}
+
+	ch := make(chan prometheus.Metric, len(page.TimeSeries))
+	scope := newScopeSeriesRecorder()
+	if err := c.reportTimeSeriesMetrics(page, descriptor, ch, time.Now(), &seriesDrops{}, nil, scope); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	scrapeTime := time.Unix(1700000000, 0)
+	c.reportScopeMetrics(scope, scrapeTime)
+
+	for projectID, want := range map[string]float64{"a": 2, "b": 1, "scope": 1} {
+		if got := testutil.ToFloat64(c.scopeSeriesMetric.WithLabelValues(projectID)); got != want {
+			t.Errorf("scope_series{project_id=%q} = %v, want %v", projectID, got, want)
+		}
+		if got := testutil.ToFloat64(c.scopeLastSeenTimestampMetric.WithLabelValues(projectID)); got != float64(scrapeTime.Unix()) {
+			t.Errorf("scope_last_seen_timestamp{project_id=%q} = %v, want %v", projectID, got, scrapeTime.Unix())
+		}
+	}
+
+	// Projects without series in a scrape drop out of scope_series but keep their last seen time.
+	c.reportScopeMetrics(newScopeSeriesRecorder(), scrapeTime.Add(time.Minute))
+	if got := testutil.CollectAndCount(c.scopeSeriesMetric); got != 0 {
+		t.Errorf("scope_series has %d series after an empty scrape, want 0", got)
+	}
+	if got := testutil.CollectAndCount(c.scopeLastSeenTimestampMetric); got != 3 {
+		t.Errorf("scope_last_seen_timestamp has %d series, want 3", got)
+	}
+}
+
+func TestReportScopeMetricsDropsProjectsLeavingTheScope(t *testing.T) {
+	t.Parallel()
+
+	scope := newMetricsScope()
+	resolve := func(_ context.Context, number string) (string, error) { return "project-" + number, nil }
+	if _, err := scope.update(context.Background(), []string{"1", "2"}, resolve); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewMonitoringCollector("scope", nil, MonitoringCollectorOptions{ScopeMetrics: true, metricsScope: scope}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scrapeTime := time.Unix(1700000000, 0)
+	recorder := newScopeSeriesRecorder()
+	for _, projectID := range []string{"scope", "project-1", "project-2"} {
+		recorder.add(projectID)
+	}
+	c.reportScopeMetrics(recorder, scrapeTime)
+
+	if _, err := scope.update(context.Background(), []string{"1"}, resolve); err != nil {
+		t.Fatal(err)
+	}
+	c.reportScopeMetrics(newScopeSeriesRecorder(), scrapeTime.Add(time.Minute))
+
+	want := `
+# HELP stackdriver_monitoring_scope_last_seen_timestamp Last time a scrape exported series for a project of the metrics scope.
+# TYPE stackdriver_monitoring_scope_last_seen_timestamp gauge
+stackdriver_monitoring_scope_last_seen_timestamp{project_id="project-1",scoping_project_id="scope"} 1.7e+09
+stackdriver_monitoring_scope_last_seen_timestamp{project_id="scope",scoping_project_id="scope"} 1.7e+09
+`
+	if err := testutil.CollectAndCompare(c.scopeLastSeenTimestampMetric, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetricsScopeUpdateResolvesNewProjectsOnly(t *testing.T) {
+	t.Parallel()
+
+	var lookups []string
+	resolve := func(_ context.Context, number string) (string, error) {
+		lookups = append(lookups, number)
+		return "project-" + number, nil
+	}
+
+	scope := newMetricsScope()
+	for _, numbers := range [][]string{{"1", "2"}, {"2", "3"}, {"1", "3"}} {
+		ids, err := scope.update(context.Background(), numbers, resolve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"project-" + numbers[0], "project-" + numbers[1]}; !slices.Equal(ids, want) {
+			t.Errorf("update(%v) = %v, want %v", numbers, ids, want)
+		}
+	}
+	// 1 left the scope in the second update, so it is looked up again.
+	if want := []string{"1", "2", "3", "1"}; !slices.Equal(lookups, want) {
+		t.Errorf("looked up %v, want %v", lookups, want)
+	}
+	if scope.includes("project-2") || !scope.includes("project-3") {
+		t.Error("includes() does not follow the last update")
+	}
+}
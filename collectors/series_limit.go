@@ -0,0 +1,94 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/hash"
+)
+
+// labeledTimeSeries is a time series together with the labels it will be
+// exported with.
+type labeledTimeSeries struct {
+	timeSeries  *monitoring.TimeSeries
+	labelKeys   []string
+	labelValues []string
+}
+
+// hash returns a hash of the exported name and labels that does not depend on
+// the label order.
+func (s *labeledTimeSeries) hash() uint64 {
+	h := hash.New()
+	h = hash.Add(h, buildFQName(s.timeSeries))
+	for _, i := range sortedLabelIndexes(s.labelKeys) {
+		h = hash.AddByte(h, hash.SeparatorByte)
+		h = hash.Add(h, s.labelKeys[i])
+		h = hash.AddByte(h, hash.SeparatorByte)
+		h = hash.Add(h, s.labelValues[i])
+	}
+	return h
+}
+
+// limitSeries keeps the limit series with the lowest label hash, so the same
+// series are kept on every scrape as long as the set of series is stable.
+func limitSeries(series []*labeledTimeSeries, limit int) []*labeledTimeSeries {
+	if len(series) <= limit {
+		return series
+	}
+	hashes := make(map[*labeledTimeSeries]uint64, len(series))
+	for _, s := range series {
+		hashes[s] = s.hash()
+	}
+	sorted := slices.Clone(series)
+	sort.Slice(sorted, func(a, b int) bool { return hashes[sorted[a]] < hashes[sorted[b]] })
+	return sorted[:limit]
+}
+
+// seriesDrops accumulates the series dropped by series limits during a scrape
+// so they can be reported with a single warning.
+type seriesDrops struct {
+	mu     sync.Mutex
+	byType map[string]int
+}
+
+func (d *seriesDrops) add(metricType string, dropped int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.byType == nil {
+		d.byType = make(map[string]int)
+	}
+	d.byType[metricType] += dropped
+}
+
+func (d *seriesDrops) warn(logger *slog.Logger) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.byType) == 0 {
+		return
+	}
+	total := 0
+	for _, dropped := range d.byType {
+		total += dropped
+	}
+	logger.Warn("Dropped series over the series limit", "dropped", total, "metric_types", d.byType)
+}
@@ -0,0 +1,126 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+func gaugeTimeSeries(metricType string, instance int) *monitoring.TimeSeries {
+	value := int64(instance)
+	return &monitoring.TimeSeries{
+		Metric:     &monitoring.Metric{Type: metricType, Labels: map[string]string{"instance": fmt.Sprintf("i-%d", instance)}},
+		Resource:   &monitoring.MonitoredResource{Type: "gce_instance", Labels: map[string]string{"zone": "us-east1-b"}},
+		MetricKind: "GAUGE",
+		ValueType:  "INT64",
+		Points: []*monitoring.Point{{
+			Interval: &monitoring.TimeInterval{EndTime: time.Now().UTC().Format(time.RFC3339Nano)},
+			Value:    &monitoring.TypedValue{Int64Value: &value},
+		}},
+	}
+}
+
+func TestLimitSeriesIsOrderIndependent(t *testing.T) {
+	t.Parallel()
+
+	var series []*labeledTimeSeries
+	for i := 0; i < 10; i++ {
+		series = append(series, &labeledTimeSeries{
+			timeSeries:  gaugeTimeSeries("custom.googleapis.com/requests", i),
+			labelKeys:   []string{"unit", "instance"},
+			labelValues: []string{"1", fmt.Sprintf("i-%d", i)},
+		})
+	}
+	reversed := slices.Clone(series)
+	slices.Reverse(reversed)
+
+	a := limitSeries(series, 3)
+	b := limitSeries(reversed, 3)
+	if len(a) != 3 {
+		t.Fatalf("limitSeries() kept %d series, want 3", len(a))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("limitSeries() kept different series depending on input order")
+		}
+	}
+}
+
+func TestSeriesLimitFor(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewMonitoringCollector("p", nil, MonitoringCollectorOptions{
+		MaxSeriesPerMetric: 100,
+		PrefixConfigs: []config.PrefixConfig{
+			{Prefix: "custom.googleapis.com/", MaxSeries: 10},
+			{Prefix: "custom.googleapis.com/noisy", MaxSeries: 1},
+		},
+	}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]int{
+		"compute.googleapis.com/instance/uptime":   100,
+		"custom.googleapis.com/requests":           10,
+		"custom.googleapis.com/noisy/queue_length": 1,
+	}
+	for metricType, want := range tests {
+		if got := c.settingsFor(metricType).maxSeries; got != want {
+			t.Errorf("settingsFor(%q).maxSeries = %d, want %d", metricType, got, want)
+		}
+	}
+}
+
+func TestReportTimeSeriesMetricsSeriesLimit(t *testing.T) {
+	t.Parallel()
+
+	const metricType = "custom.googleapis.com/requests"
+	c, err := NewMonitoringCollector("fake-project", nil, MonitoringCollectorOptions{MaxSeriesPerMetric: 2}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &monitoring.ListTimeSeriesResponse{}
+	for i := 0; i < 5; i++ {
+		page.TimeSeries = append(page.TimeSeries, gaugeTimeSeries(metricType, i))
+	}
+	descriptor := &monitoring.MetricDescriptor{Type: metricType, Unit: "1"}
+
+	ch := make(chan prometheus.Metric, len(page.TimeSeries))
+	drops := &seriesDrops{}
+	if err := c.reportTimeSeriesMetrics(page, descriptor, ch, time.Now(), drops, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	if got := len(ch); got != 2 {
+		t.Errorf("exported %d series, want 2", got)
+	}
+	if got := testutil.ToFloat64(c.seriesDroppedTotalMetric.WithLabelValues(metricType)); got != 3 {
+		t.Errorf("series_dropped_total = %v, want 3", got)
+	}
+	if drops.byType[metricType] != 3 {
+		t.Errorf("seriesDrops = %v, want 3 for %s", drops.byType, metricType)
+	}
+}
@@ -16,6 +16,7 @@ package collectors
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	"github.com/PuerkitoBio/rehttp"
 	"golang.org/x/oauth2/google"
@@ -29,6 +30,9 @@ func monitoringCollectorOptionsForPrefixes(cfg *config.Config, metricPrefixes []
 	return MonitoringCollectorOptions{
 		MetricTypePrefixes:        metricPrefixes,
 		ExtraFilters:              ParseMetricExtraFilters(cfg.Filters),
+		PrefixConfigs:             cfg.PrefixConfigs,
+		DescriptorSelector:        descriptorSelector(cfg),
+		MaxSeriesPerMetric:        cfg.MaxSeriesPerMetric,
 		RequestInterval:           cfg.MetricsInterval,
 		RequestOffset:             cfg.MetricsOffset,
 		IngestDelay:               cfg.MetricsIngestDelay,
@@ -37,6 +41,29 @@ func monitoringCollectorOptionsForPrefixes(cfg *config.Config, metricPrefixes []
 		AggregateDeltas:           cfg.AggregateDeltas,
 		DescriptorCacheTTL:        cfg.DescriptorCacheTTL,
 		DescriptorCacheOnlyGoogle: cfg.DescriptorCacheOnlyGoogle,
+		LabelCollisionStrategy:    cfg.LabelCollisionStrategy,
+		DescriptorInfoMetric:      cfg.DescriptorInfoMetric,
+		HelpIncludeMetricType:     cfg.HelpIncludeMetricType,
+		CardinalityStats:          cfg.CardinalityStats,
+	}
+}
+
+// descriptorSelector builds the DescriptorSelector of a validated Config.
+// Exclude regexes are fully anchored.
+func descriptorSelector(cfg *config.Config) DescriptorSelector {
+	excludeRegexes := make([]*regexp.Regexp, 0, len(cfg.MetricsExcludeRegexes))
+	for _, re := range cfg.MetricsExcludeRegexes {
+		excludeRegexes = append(excludeRegexes, regexp.MustCompile("^(?:"+re+")$"))
+	}
+	return DescriptorSelector{
+		ExcludePrefixes:     cfg.MetricsExcludePrefixes,
+		ExcludeRegexes:      excludeRegexes,
+		IncludeLaunchStages: cfg.IncludeLaunchStages,
+		ExcludeLaunchStages: cfg.ExcludeLaunchStages,
+		IncludeMetricKinds:  cfg.IncludeMetricKinds,
+		ExcludeMetricKinds:  cfg.ExcludeMetricKinds,
+		IncludeValueTypes:   cfg.IncludeValueTypes,
+		ExcludeValueTypes:   cfg.ExcludeValueTypes,
 	}
 }
 
@@ -0,0 +1,63 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"strings"
+	"time"
+
+	"google.golang.org/api/monitoring/v3"
+)
+
+// IsParentTarget reports whether a collector target is a folder or an
+// organization, as opposed to a project ID.
+func IsParentTarget(target string) bool {
+	return strings.HasPrefix(target, "folders/") || strings.HasPrefix(target, "organizations/")
+}
+
+// descriptorProject returns the project metric descriptors are listed from.
+// Metric descriptors can only be listed per project, so folder and
+// organization targets list them from their descriptor project.
+func (c *MonitoringCollector) descriptorProject() string {
+	if IsParentTarget(c.projectID) {
+		return c.descriptorProjectID
+	}
+	return c.projectID
+}
+
+// listTimeSeriesPage returns a single page of time series of the collector
+// target, which can be a project, a folder or an organization.
+func (c *MonitoringCollector) listTimeSeriesPage(filter string, startTime, endTime time.Time, pageToken string) (*monitoring.ListTimeSeriesResponse, error) {
+	start, end := startTime.Format(time.RFC3339Nano), endTime.Format(time.RFC3339Nano)
+	switch {
+	case strings.HasPrefix(c.projectID, "folders/"):
+		call := c.monitoringService.Folders.TimeSeries.List(c.projectID).Filter(filter).IntervalStartTime(start).IntervalEndTime(end)
+		if pageToken != "" {
+			call.PageToken(pageToken)
+		}
+		return call.Do()
+	case strings.HasPrefix(c.projectID, "organizations/"):
+		call := c.monitoringService.Organizations.TimeSeries.List(c.projectID).Filter(filter).IntervalStartTime(start).IntervalEndTime(end)
+		if pageToken != "" {
+			call.PageToken(pageToken)
+		}
+		return call.Do()
+	default:
+		call := c.monitoringService.Projects.TimeSeries.List(projectResource(c.projectID)).Filter(filter).IntervalStartTime(start).IntervalEndTime(end)
+		if pageToken != "" {
+			call.PageToken(pageToken)
+		}
+		return call.Do()
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+)
+
+func TestListTimeSeriesPageTargets(t *testing.T) {
+	t.Parallel()
+
+	var gotPath, gotPageToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotPageToken = r.URL.Query().Get("pageToken")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"timeSeries": []}`))
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := monitoring.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target   string
+		wantPath string
+	}{
+		{target: "my-project", wantPath: "/v3/projects/my-project/timeSeries"},
+		{target: "folders/123", wantPath: "/v3/folders/123/timeSeries"},
+		{target: "organizations/456", wantPath: "/v3/organizations/456/timeSeries"},
+	}
+	for _, tt := range tests {
+		c, err := NewMonitoringCollector(tt.target, service, MonitoringCollectorOptions{DescriptorProjectID: "descriptors"}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.listTimeSeriesPage(`metric.type="a"`, time.Now().Add(-time.Minute), time.Now(), "next"); err != nil {
+			t.Fatalf("listTimeSeriesPage(%s): %v", tt.target, err)
+		}
+		if gotPath != tt.wantPath {
+			t.Errorf("listTimeSeriesPage(%s) requested %s, want %s", tt.target, gotPath, tt.wantPath)
+		}
+		if gotPageToken != "next" {
+			t.Errorf("listTimeSeriesPage(%s) sent page token %q, want %q", tt.target, gotPageToken, "next")
+		}
+	}
+}
+
+func TestDescriptorProject(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewMonitoringCollector("folders/123", nil, MonitoringCollectorOptions{}, slog.Default(), nopCounterStore{}, nopHistogramStore{}); err == nil {
+		t.Error("expected error for a folder target without descriptor project, got nil")
+	}
+
+	for target, want := range map[string]string{"my-project": "my-project", "folders/123": "descriptors"} {
+		c, err := NewMonitoringCollector(target, nil, MonitoringCollectorOptions{DescriptorProjectID: "descriptors"}, slog.Default(), nopCounterStore{}, nopHistogramStore{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.descriptorProject(); got != want {
+			t.Errorf("descriptorProject() for %s = %q, want %q", target, got, want)
+		}
+	}
+}
@@ -13,12 +13,16 @@
 
 // Package config is the pure value-type definition of the stackdriver_exporter
 // configuration. It has no dependencies on the collectors package or any GCP
-// client libraries.
+// client libraries, and does no I/O: configuration files are read by the
+// caller. Per-prefix settings embed the value types of the relabel package.
 package config
 
 import (
 	"fmt"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -33,18 +37,100 @@ const (
 	DefaultMetricsIngest        = false
 	DefaultFillMissing          = true
 	DefaultDropDelegated        = false
+	DefaultDeduplicateProjects  = false
+	DefaultOwnershipClaimTTL    = 15 * time.Minute
 	DefaultAggregateDeltas      = false
+	DefaultDeltasZeroSeed       = false
 	DefaultDeltasTTL            = 30 * time.Minute
 	DefaultDescriptorTTL        = 0 * time.Second
 	DefaultDescriptorGoogleOnly = true
+
+	DefaultLabelCollisionStrategy = LabelCollisionDrop
+	DefaultDescriptorInfoMetric   = false
+	DefaultMaxSeriesPerMetric     = 0
+	DefaultHelpIncludeMetricType  = false
+	DefaultCardinalityStats       = false
+	DefaultProjectEnrichment      = false
+	DefaultProjectEnrichmentTTL   = 1 * time.Hour
+	DefaultDeltaStore             = DeltaStoreMemory
+	DefaultDeltaStoreSnapshot     = 1 * time.Minute
+	DefaultDeltaRedisPrefix       = "stackdriver_exporter:"
+	DefaultDeltaStoreMaxSeries    = 0
+	DefaultDeltaSweepInterval     = 1 * time.Minute
+	DefaultDeltaBucketChange      = BucketChangeReset
+)
+
+// Strategies for a monitored resource label whose key is already used by a
+// metric label.
+const (
+	// LabelCollisionDrop keeps the metric label and drops the resource label.
+	LabelCollisionDrop = "drop"
+	// LabelCollisionPrefixResource exports the resource label as resource_<key>.
+	LabelCollisionPrefixResource = "prefix-resource"
+	// LabelCollisionPrefixMetric exports the metric label as metric_<key>.
+	LabelCollisionPrefixMetric = "prefix-metric"
+	// LabelCollisionError fails the scrape of the affected metric type.
+	LabelCollisionError = "error"
 )
 
+// LabelCollisionStrategies lists the accepted LabelCollisionStrategy values.
+var LabelCollisionStrategies = []string{
+	LabelCollisionDrop,
+	LabelCollisionPrefixResource,
+	LabelCollisionPrefixMetric,
+	LabelCollisionError,
+}
+
+const (
+	// ProjectFieldDisplayName exports the project display name as project_display_name.
+	ProjectFieldDisplayName = "display_name"
+	// ProjectFieldNumber exports the project number as project_number.
+	ProjectFieldNumber = "number"
+	// ProjectFieldFolderPath exports the display names of the parent folders as project_folder_path.
+	ProjectFieldFolderPath = "folder_path"
+)
+
+const (
+	// DeltaStoreMemory keeps aggregated DELTA metrics in memory only.
+	DeltaStoreMemory = "memory"
+	// DeltaStoreFile snapshots aggregated DELTA metrics to a file.
+	DeltaStoreFile = "file"
+	// DeltaStoreBolt snapshots aggregated DELTA metrics to a bbolt database.
+	DeltaStoreBolt = "bolt"
+	// DeltaStoreRedis keeps aggregated DELTA metrics in a Redis server shared by replicas.
+	DeltaStoreRedis = "redis"
+)
+
+const (
+	// BucketChangeReset starts an aggregated histogram over when its bucket bounds change.
+	BucketChangeReset = "reset"
+	// BucketChangeRebucket interpolates an aggregated histogram onto its new bucket bounds.
+	BucketChangeRebucket = "rebucket"
+)
+
+// BucketChanges lists the accepted DeltaBucketChange values.
+var BucketChanges = []string{BucketChangeReset, BucketChangeRebucket}
+
+// DeltaStores lists the accepted DeltaStore values.
+var DeltaStores = []string{DeltaStoreMemory, DeltaStoreFile, DeltaStoreBolt, DeltaStoreRedis}
+
+// ProjectFields lists the accepted ProjectEnrichmentFields values.
+var ProjectFields = []string{ProjectFieldDisplayName, ProjectFieldNumber, ProjectFieldFolderPath}
+
 // DefaultRetryStatuses must be treated as immutable after declaration.
 var DefaultRetryStatuses = []int{http.StatusServiceUnavailable}
 
+// DefaultProjectsExcludeStates must be treated as immutable after declaration.
+var DefaultProjectsExcludeStates = []string{"DELETE_REQUESTED"}
+
 type Config struct {
 	ProjectIDs                []string
 	ProjectsFilter            string
+	ProjectsRefreshInterval   time.Duration
+	ProjectsParents           []string
+	ProjectsExcludeLabels     []string
+	ProjectsExcludeRegexes    []string
+	ProjectsExcludeStates     []string
 	UniverseDomain            string
 	MaxRetries                int
 	HTTPTimeout               time.Duration
@@ -52,16 +138,49 @@ type Config struct {
 	BackoffJitter             time.Duration
 	RetryStatuses             []int
 	MetricsPrefixes           []string
+	MetricsExcludePrefixes    []string
+	MetricsExcludeRegexes     []string
+	IncludeLaunchStages       []string
+	ExcludeLaunchStages       []string
+	IncludeMetricKinds        []string
+	ExcludeMetricKinds        []string
+	IncludeValueTypes         []string
+	ExcludeValueTypes         []string
 	MetricsInterval           time.Duration
 	MetricsOffset             time.Duration
 	MetricsIngestDelay        bool
 	FillMissingLabels         bool
 	DropDelegatedProjects     bool
+	DeduplicateProjects       bool
+	OwnershipClaimTTL         time.Duration
+	ScopingProjectID          string
+	TargetParents             []string
+	TargetDescriptorProjectID string
+	ProjectEnrichment         bool
+	ProjectEnrichmentFields   []string
+	ProjectEnrichmentLabels   []string
+	ProjectEnrichmentTTL      time.Duration
 	Filters                   []string
 	AggregateDeltas           bool
 	AggregateDeltasTTL        time.Duration
+	AggregateDeltasZeroSeed   bool
+	DeltaStore                string
+	DeltaStorePath            string
+	DeltaSnapshotInterval     time.Duration
+	DeltaRedisURL             string
+	DeltaRedisPrefix          string
+	DeltaStoreMaxSeries       int
+	DeltaSweepInterval        time.Duration
+	DeltaBucketChange         string
 	DescriptorCacheTTL        time.Duration
 	DescriptorCacheOnlyGoogle bool
+	LabelCollisionStrategy    string
+	MaxSeriesPerMetric        int
+	DescriptorInfoMetric      bool
+	HelpIncludeMetricType     bool
+	CardinalityStats          bool
+	// PrefixConfigs holds per-prefix settings such as metric relabeling.
+	PrefixConfigs []PrefixConfig
 
 	// validated is set by Validate on success.
 	validated bool
@@ -78,15 +197,32 @@ func NewConfigWithDefaults() *Config {
 		MaxBackoff:                DefaultMaxBackoff,
 		BackoffJitter:             DefaultBackoffJitter,
 		RetryStatuses:             append([]int(nil), DefaultRetryStatuses...),
+		ProjectsExcludeStates:     slices.Clone(DefaultProjectsExcludeStates),
 		MetricsInterval:           DefaultMetricsInterval,
 		MetricsOffset:             DefaultMetricsOffset,
 		MetricsIngestDelay:        DefaultMetricsIngest,
 		FillMissingLabels:         DefaultFillMissing,
 		DropDelegatedProjects:     DefaultDropDelegated,
+		DeduplicateProjects:       DefaultDeduplicateProjects,
+		OwnershipClaimTTL:         DefaultOwnershipClaimTTL,
 		AggregateDeltas:           DefaultAggregateDeltas,
 		AggregateDeltasTTL:        DefaultDeltasTTL,
+		AggregateDeltasZeroSeed:   DefaultDeltasZeroSeed,
+		DeltaStore:                DefaultDeltaStore,
+		DeltaSnapshotInterval:     DefaultDeltaStoreSnapshot,
+		DeltaRedisPrefix:          DefaultDeltaRedisPrefix,
+		DeltaStoreMaxSeries:       DefaultDeltaStoreMaxSeries,
+		DeltaSweepInterval:        DefaultDeltaSweepInterval,
+		DeltaBucketChange:         DefaultDeltaBucketChange,
 		DescriptorCacheTTL:        DefaultDescriptorTTL,
 		DescriptorCacheOnlyGoogle: DefaultDescriptorGoogleOnly,
+		LabelCollisionStrategy:    DefaultLabelCollisionStrategy,
+		MaxSeriesPerMetric:        DefaultMaxSeriesPerMetric,
+		DescriptorInfoMetric:      DefaultDescriptorInfoMetric,
+		HelpIncludeMetricType:     DefaultHelpIncludeMetricType,
+		CardinalityStats:          DefaultCardinalityStats,
+		ProjectEnrichment:         DefaultProjectEnrichment,
+		ProjectEnrichmentTTL:      DefaultProjectEnrichmentTTL,
 	}
 }
 
@@ -96,6 +232,91 @@ func (c *Config) Validate() error {
 	if len(c.MetricsPrefixes) == 0 {
 		return fmt.Errorf("metrics_prefixes must have at least one entry")
 	}
+	if c.LabelCollisionStrategy != "" && !slices.Contains(LabelCollisionStrategies, c.LabelCollisionStrategy) {
+		return fmt.Errorf("label_collision_strategy must be one of %v, got %q", LabelCollisionStrategies, c.LabelCollisionStrategy)
+	}
+	if c.ScopingProjectID != "" && c.DropDelegatedProjects {
+		return fmt.Errorf("scoping_project_id cannot be combined with drop_delegated_projects")
+	}
+	for _, parent := range c.ProjectsParents {
+		if !strings.HasPrefix(parent, "folders/") && !strings.HasPrefix(parent, "organizations/") {
+			return fmt.Errorf("projects_parents entry %q must be folders/<id> or organizations/<id>", parent)
+		}
+	}
+	for _, label := range c.ProjectsExcludeLabels {
+		if key, _, _ := strings.Cut(label, "="); key == "" {
+			return fmt.Errorf("projects_exclude_labels entry %q must be key or key=value", label)
+		}
+	}
+	for _, re := range c.ProjectsExcludeRegexes {
+		if _, err := regexp.Compile(re); err != nil {
+			return fmt.Errorf("invalid projects_exclude_regexes entry %q: %w", re, err)
+		}
+	}
+	for _, parent := range c.TargetParents {
+		if !strings.HasPrefix(parent, "folders/") && !strings.HasPrefix(parent, "organizations/") {
+			return fmt.Errorf("target_parents entry %q must be folders/<id> or organizations/<id>", parent)
+		}
+	}
+	for _, field := range c.ProjectEnrichmentFields {
+		if !slices.Contains(ProjectFields, field) {
+			return fmt.Errorf("project_enrichment_fields entry %q must be one of %v", field, ProjectFields)
+		}
+	}
+	if c.DeduplicateProjects && c.OwnershipClaimTTL <= 0 {
+		return fmt.Errorf("ownership_claim_ttl must be positive")
+	}
+	if c.ProjectEnrichment && c.ProjectEnrichmentTTL <= 0 {
+		return fmt.Errorf("project_enrichment_ttl must be positive")
+	}
+	if c.DeltaStore != "" && !slices.Contains(DeltaStores, c.DeltaStore) {
+		return fmt.Errorf("delta_store must be one of %v, got %q", DeltaStores, c.DeltaStore)
+	}
+	if c.DeltaStore == DeltaStoreFile || c.DeltaStore == DeltaStoreBolt {
+		if c.DeltaStorePath == "" {
+			return fmt.Errorf("delta_store_path is required with delta_store %q", c.DeltaStore)
+		}
+		if c.DeltaSnapshotInterval <= 0 {
+			return fmt.Errorf("delta_snapshot_interval must be positive")
+		}
+	}
+	if c.DeltaStore == DeltaStoreRedis && c.DeltaRedisURL == "" {
+		return fmt.Errorf("delta_redis_url is required with delta_store %q", c.DeltaStore)
+	}
+	if c.DeltaStore == DeltaStoreRedis && c.AggregateDeltasTTL <= 0 {
+		return fmt.Errorf("aggregate_deltas_ttl must be positive with delta_store %q", c.DeltaStore)
+	}
+	if c.DeltaStore == DeltaStoreRedis && c.AggregateDeltasZeroSeed {
+		return fmt.Errorf("aggregate_deltas_zero_seed is not supported with delta_store %q", c.DeltaStore)
+	}
+	if c.DeltaBucketChange != "" && !slices.Contains(BucketChanges, c.DeltaBucketChange) {
+		return fmt.Errorf("delta_bucket_change must be one of %v, got %q", BucketChanges, c.DeltaBucketChange)
+	}
+	if c.DeltaStore == DeltaStoreRedis && c.DeltaBucketChange == BucketChangeRebucket {
+		return fmt.Errorf("delta_bucket_change %q is not supported with delta_store %q", c.DeltaBucketChange, c.DeltaStore)
+	}
+	if c.DeltaStoreMaxSeries < 0 {
+		return fmt.Errorf("delta_store_max_series must not be negative")
+	}
+	if c.DeltaSweepInterval < 0 {
+		return fmt.Errorf("delta_sweep_interval must not be negative")
+	}
+	if c.ProjectsRefreshInterval < 0 {
+		return fmt.Errorf("projects_refresh_interval must not be negative")
+	}
+	if c.MaxSeriesPerMetric < 0 {
+		return fmt.Errorf("max_series_per_metric must not be negative")
+	}
+	for _, re := range c.MetricsExcludeRegexes {
+		if _, err := regexp.Compile(re); err != nil {
+			return fmt.Errorf("invalid metrics_exclude_regexes entry %q: %w", re, err)
+		}
+	}
+	for i := range c.PrefixConfigs {
+		if err := c.PrefixConfigs[i].Validate(); err != nil {
+			return fmt.Errorf("invalid prefix config: %w", err)
+		}
+	}
 	c.validated = true
 	return nil
 }
@@ -28,6 +28,138 @@ func TestValidate(t *testing.T) {
 			cfg:     Config{},
 			wantErr: true,
 		},
+		{
+			name: "unknown label collision strategy",
+			cfg: Config{
+				MetricsPrefixes:        []string{"compute.googleapis.com/"},
+				LabelCollisionStrategy: "rename",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid label collision strategy",
+			cfg: Config{
+				MetricsPrefixes:        []string{"compute.googleapis.com/"},
+				LabelCollisionStrategy: LabelCollisionPrefixResource,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid exclude regex",
+			cfg: Config{
+				MetricsPrefixes:       []string{"compute.googleapis.com/"},
+				MetricsExcludeRegexes: []string{"compute.googleapis.com/(instance"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scoping project with drop delegated projects",
+			cfg: Config{
+				MetricsPrefixes:       []string{"compute.googleapis.com/"},
+				ScopingProjectID:      "scope",
+				DropDelegatedProjects: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid projects parent",
+			cfg: Config{
+				MetricsPrefixes: []string{"compute.googleapis.com/"},
+				ProjectsParents: []string{"projects/my-project"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid projects exclude regex",
+			cfg: Config{
+				MetricsPrefixes:        []string{"compute.googleapis.com/"},
+				ProjectsParents:        []string{"folders/123"},
+				ProjectsExcludeRegexes: []string{"sandbox-(.*"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid projects parents",
+			cfg: Config{
+				MetricsPrefixes:       []string{"compute.googleapis.com/"},
+				ProjectsParents:       []string{"folders/123", "organizations/456"},
+				ProjectsExcludeLabels: []string{"env=dev", "ephemeral"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid target parent",
+			cfg: Config{
+				MetricsPrefixes: []string{"compute.googleapis.com/"},
+				TargetParents:   []string{"my-project"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown project enrichment field",
+			cfg: Config{
+				MetricsPrefixes:         []string{"compute.googleapis.com/"},
+				ProjectEnrichment:       true,
+				ProjectEnrichmentFields: []string{"owner"},
+				ProjectEnrichmentTTL:    DefaultProjectEnrichmentTTL,
+			},
+			wantErr: true,
+		},
+		{
+			name: "deduplicated projects without an ownership claim TTL",
+			cfg: Config{
+				MetricsPrefixes:     []string{"compute.googleapis.com/"},
+				DeduplicateProjects: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "deduplicated projects with an ownership claim TTL",
+			cfg: Config{
+				MetricsPrefixes:     []string{"compute.googleapis.com/"},
+				DeduplicateProjects: true,
+				OwnershipClaimTTL:   DefaultOwnershipClaimTTL,
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero seeded redis delta store",
+			cfg: Config{
+				MetricsPrefixes:         []string{"compute.googleapis.com/"},
+				DeltaStore:              DeltaStoreRedis,
+				DeltaRedisURL:           "redis://localhost:6379/0",
+				AggregateDeltasTTL:      DefaultDeltasTTL,
+				AggregateDeltasZeroSeed: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "redis delta store without a TTL",
+			cfg: Config{
+				MetricsPrefixes: []string{"compute.googleapis.com/"},
+				DeltaStore:      DeltaStoreRedis,
+				DeltaRedisURL:   "redis://localhost:6379/0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid redis delta store",
+			cfg: Config{
+				MetricsPrefixes:    []string{"compute.googleapis.com/"},
+				DeltaStore:         DeltaStoreRedis,
+				DeltaRedisURL:      "redis://localhost:6379/0",
+				AggregateDeltasTTL: DefaultDeltasTTL,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown delta bucket change",
+			cfg: Config{
+				MetricsPrefixes:   []string{"compute.googleapis.com/"},
+				DeltaBucketChange: "merge",
+			},
+			wantErr: true,
+		},
 		{
 			name:    "valid with single prefix",
 			cfg:     Config{MetricsPrefixes: []string{"compute.googleapis.com/"}},
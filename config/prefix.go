@@ -0,0 +1,159 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+
+	"github.com/prometheus-community/stackdriver_exporter/relabel"
+)
+
+// PrefixConfig holds settings that apply to every metric type starting with
+// Prefix. When several entries match a metric type, list-valued settings are
+// applied in file order.
+type PrefixConfig struct {
+	// Prefix is the metric type prefix the settings apply to.
+	Prefix string `yaml:"prefix"`
+	// MetricRelabelConfigs are relabel rules applied to each series before it is exported.
+	MetricRelabelConfigs []*relabel.Config `yaml:"metric_relabel_configs,omitempty"`
+	// MetadataSystemLabels are the monitored resource system metadata labels exported as metadata_system_<key>.
+	MetadataSystemLabels []string `yaml:"metadata_system_labels,omitempty"`
+	// MetadataUserLabels are the monitored resource user labels exported as metadata_user_<key>.
+	MetadataUserLabels []string `yaml:"metadata_user_labels,omitempty"`
+	// MaxSeries overrides Config.MaxSeriesPerMetric for matching metric types. The longest matching prefix wins,
+	// 0 keeps the global limit.
+	MaxSeries int `yaml:"max_series,omitempty"`
+	// DeltaMode decides how DELTA metrics of matching metric types are exported, one of DeltaModes. The longest
+	// matching prefix wins, empty follows Config.AggregateDeltas.
+	DeltaMode string `yaml:"delta_mode,omitempty"`
+	// GaugePolicies decide which values are exported for the points of a GAUGE series in the request interval,
+	// instead of the newest one. The longest matching prefix wins.
+	GaugePolicies []GaugePolicyConfig `yaml:"gauge_policies,omitempty"`
+	// HistogramBounds are the bucket upper bounds distributions of matching metric types are re-bucketed onto.
+	// The longest prefix setting HistogramBounds or HistogramMaxBuckets wins.
+	HistogramBounds []float64 `yaml:"histogram_bounds,omitempty"`
+	// HistogramMaxBuckets is the maximum number of buckets, including the +Inf one, of distributions of matching
+	// metric types. Adjacent buckets are merged to stay within it.
+	HistogramMaxBuckets int `yaml:"histogram_max_buckets,omitempty"`
+	// SummaryQuantiles, if set, exports distributions of matching metric types as summaries with these quantiles
+	// instead of histograms. The longest matching prefix wins.
+	SummaryQuantiles []float64 `yaml:"summary_quantiles,omitempty"`
+}
+
+// GaugePolicyConfig exports a value computed from the points of a GAUGE series
+// under the metric name followed by Suffix.
+type GaugePolicyConfig struct {
+	// Policy is one of GaugePolicies.
+	Policy string `yaml:"policy"`
+	Suffix string `yaml:"suffix,omitempty"`
+}
+
+const (
+	// DeltaModeGauge exports the newest point of a DELTA series as a gauge.
+	DeltaModeGauge = "gauge"
+	// DeltaModeAggregate adds the points of a DELTA series to a counter kept by the exporter.
+	DeltaModeAggregate = "aggregate"
+	// DeltaModeRate exports the newest point of a DELTA series divided by the length of its interval, as a
+	// per-second gauge.
+	DeltaModeRate = "rate"
+)
+
+// Gauge policies compute the exported value of a GAUGE series from its points:
+// the newest, the largest, the smallest, their mean, their sum or their number.
+const (
+	GaugePolicyNewest = "newest"
+	GaugePolicyMax    = "max"
+	GaugePolicyMin    = "min"
+	GaugePolicyMean   = "mean"
+	GaugePolicySum    = "sum"
+	GaugePolicyCount  = "count"
+)
+
+// GaugePolicies are the accepted values of GaugePolicyConfig.Policy.
+var GaugePolicies = []string{
+	GaugePolicyNewest,
+	GaugePolicyMax,
+	GaugePolicyMin,
+	GaugePolicyMean,
+	GaugePolicySum,
+	GaugePolicyCount,
+}
+
+var gaugePolicySuffixRE = regexp.MustCompile(`^[a-zA-Z0-9_]*$`)
+
+// DeltaModes are the accepted values of PrefixConfig.DeltaMode.
+var DeltaModes = []string{
+	DeltaModeGauge,
+	DeltaModeAggregate,
+	DeltaModeRate,
+}
+
+// Validate reports errors in a single prefix entry.
+func (p *PrefixConfig) Validate() error {
+	if p.Prefix == "" {
+		return fmt.Errorf("prefix must not be empty")
+	}
+	for _, rc := range p.MetricRelabelConfigs {
+		if rc == nil {
+			return fmt.Errorf("prefix %q: empty metric_relabel_configs entry", p.Prefix)
+		}
+		if err := rc.Validate(); err != nil {
+			return fmt.Errorf("prefix %q: %w", p.Prefix, err)
+		}
+	}
+	if p.MaxSeries < 0 {
+		return fmt.Errorf("prefix %q: max_series must not be negative", p.Prefix)
+	}
+	if p.DeltaMode != "" && !slices.Contains(DeltaModes, p.DeltaMode) {
+		return fmt.Errorf("prefix %q: delta_mode must be one of %v, got %q", p.Prefix, DeltaModes, p.DeltaMode)
+	}
+	suffixes := make(map[string]struct{}, len(p.GaugePolicies))
+	for _, gp := range p.GaugePolicies {
+		if !slices.Contains(GaugePolicies, gp.Policy) {
+			return fmt.Errorf("prefix %q: gauge_policies policy must be one of %v, got %q", p.Prefix, GaugePolicies, gp.Policy)
+		}
+		if !gaugePolicySuffixRE.MatchString(gp.Suffix) {
+			return fmt.Errorf("prefix %q: gauge_policies suffix %q must only contain letters, digits and underscores", p.Prefix, gp.Suffix)
+		}
+		if _, ok := suffixes[gp.Suffix]; ok {
+			return fmt.Errorf("prefix %q: gauge_policies suffix %q is used more than once", p.Prefix, gp.Suffix)
+		}
+		suffixes[gp.Suffix] = struct{}{}
+	}
+	if len(p.HistogramBounds) > 0 && p.HistogramMaxBuckets != 0 {
+		return fmt.Errorf("prefix %q: histogram_bounds and histogram_max_buckets are mutually exclusive", p.Prefix)
+	}
+	for i, bound := range p.HistogramBounds {
+		if math.IsNaN(bound) || (i > 0 && bound <= p.HistogramBounds[i-1]) {
+			return fmt.Errorf("prefix %q: histogram_bounds must be increasing numbers", p.Prefix)
+		}
+	}
+	if p.HistogramMaxBuckets < 0 || p.HistogramMaxBuckets == 1 {
+		return fmt.Errorf("prefix %q: histogram_max_buckets must be 0 or at least 2", p.Prefix)
+	}
+	for _, q := range p.SummaryQuantiles {
+		if !(q >= 0 && q <= 1) {
+			return fmt.Errorf("prefix %q: summary_quantiles must be between 0 and 1, got %v", p.Prefix, q)
+		}
+	}
+	for _, key := range append(slices.Clone(p.MetadataSystemLabels), p.MetadataUserLabels...) {
+		if key == "" {
+			return fmt.Errorf("prefix %q: empty metadata label key", p.Prefix)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/prometheus-community/stackdriver_exporter/relabel"
+)
+
+func TestValidatePrefixConfigs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		prefix  PrefixConfig
+		wantErr bool
+	}{
+		{
+			name:    "empty prefix",
+			prefix:  PrefixConfig{},
+			wantErr: true,
+		},
+		{
+			name: "invalid relabel config",
+			prefix: PrefixConfig{
+				Prefix:               "compute.googleapis.com/",
+				MetricRelabelConfigs: []*relabel.Config{{Action: relabel.Replace, Regex: relabel.MustNewRegexp("(.*)")}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown delta mode",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", DeltaMode: "counter"},
+			wantErr: true,
+		},
+		{
+			name:    "rate delta mode",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", DeltaMode: DeltaModeRate},
+			wantErr: false,
+		},
+		{
+			name:    "unknown gauge policy",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", GaugePolicies: []GaugePolicyConfig{{Policy: "p99"}}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate gauge policy suffix",
+			prefix: PrefixConfig{Prefix: "compute.googleapis.com/", GaugePolicies: []GaugePolicyConfig{
+				{Policy: GaugePolicyNewest},
+				{Policy: GaugePolicyMax},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid gauge policy suffix",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", GaugePolicies: []GaugePolicyConfig{{Policy: GaugePolicyMax, Suffix: "-max"}}},
+			wantErr: true,
+		},
+		{
+			name: "several gauge policies",
+			prefix: PrefixConfig{Prefix: "compute.googleapis.com/", GaugePolicies: []GaugePolicyConfig{
+				{Policy: GaugePolicyNewest},
+				{Policy: GaugePolicyMax, Suffix: "_max"},
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "decreasing histogram bounds",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", HistogramBounds: []float64{1, 10, 5}},
+			wantErr: true,
+		},
+		{
+			name:    "histogram bounds and max buckets",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", HistogramBounds: []float64{1, 10}, HistogramMaxBuckets: 5},
+			wantErr: true,
+		},
+		{
+			name:    "single histogram bucket",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", HistogramMaxBuckets: 1},
+			wantErr: true,
+		},
+		{
+			name:    "histogram bounds",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", HistogramBounds: []float64{0.1, 1, 10}},
+			wantErr: false,
+		},
+		{
+			name:    "summary quantile above 1",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", SummaryQuantiles: []float64{0.5, 99}},
+			wantErr: true,
+		},
+		{
+			name:    "summary quantiles",
+			prefix:  PrefixConfig{Prefix: "compute.googleapis.com/", SummaryQuantiles: []float64{0.5, 0.9, 0.99}},
+			wantErr: false,
+		},
+		{
+			name: "valid relabel config",
+			prefix: PrefixConfig{
+				Prefix:               "compute.googleapis.com/",
+				MetricRelabelConfigs: []*relabel.Config{{Action: relabel.LabelDrop, Regex: relabel.MustNewRegexp("instance_id")}},
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := Config{
+				MetricsPrefixes: []string{"compute.googleapis.com/"},
+				PrefixConfigs:   []PrefixConfig{tt.prefix},
+			}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() err = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
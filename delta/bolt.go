@@ -0,0 +1,116 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delta
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/prometheus-community/stackdriver_exporter/collectors"
+)
+
+var (
+	countersBucket   = []byte("counters")
+	histogramsBucket = []byte("histograms")
+)
+
+// BoltBackend saves snapshots to a bbolt database, one key per metric
+// descriptor name.
+type BoltBackend struct {
+	db *bolt.DB
+}
+
+// NewBoltBackend opens or creates the bbolt database at path.
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return &BoltBackend{db: db}, nil
+}
+
+func (b *BoltBackend) Load() (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Counters:   make(map[string][]*collectors.ConstMetric),
+		Histograms: make(map[string][]*collectors.HistogramMetric),
+	}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(countersBucket); bucket != nil {
+			if err := bucket.ForEach(func(k, v []byte) error {
+				var metrics []*collectors.ConstMetric
+				if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&metrics); err != nil {
+					return fmt.Errorf("decoding counters of %s: %w", k, err)
+				}
+				snapshot.Counters[string(k)] = metrics
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		if bucket := tx.Bucket(histogramsBucket); bucket != nil {
+			return bucket.ForEach(func(k, v []byte) error {
+				var metrics []*collectors.HistogramMetric
+				if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&metrics); err != nil {
+					return fmt.Errorf("decoding histograms of %s: %w", k, err)
+				}
+				snapshot.Histograms[string(k)] = metrics
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (b *BoltBackend) Save(snapshot *Snapshot) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := replaceBucket(tx, countersBucket, snapshot.Counters); err != nil {
+			return err
+		}
+		return replaceBucket(tx, histogramsBucket, snapshot.Histograms)
+	})
+}
+
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
+
+func replaceBucket[T any](tx *bolt.Tx, name []byte, entries map[string][]T) error {
+	if tx.Bucket(name) != nil {
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+	}
+	bucket, err := tx.CreateBucket(name)
+	if err != nil {
+		return err
+	}
+	for descriptorName, metrics := range entries {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(metrics); err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte(descriptorName), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/api/monitoring/v3"
 
 	"github.com/prometheus-community/stackdriver_exporter/collectors"
@@ -30,20 +31,41 @@ import (
 type MetricEntry struct {
 	Collected map[uint64]*collectors.ConstMetric
 	mutex     *sync.RWMutex
+	// seeding holds the keys of new counters whose zero sample has not been listed yet.
+	seeding map[uint64]struct{}
 }
 
 type InMemoryCounterStore struct {
-	store  *sync.Map
-	ttl    time.Duration
-	logger *slog.Logger
+	store   *sync.Map
+	ttl     time.Duration
+	opts    StoreOptions
+	lru     *seriesLRU
+	metrics *storeMetrics
+	logger  *slog.Logger
 }
 
 // NewInMemoryCounterStore returns an implementation of CounterStore which is persisted in-memory
 func NewInMemoryCounterStore(logger *slog.Logger, ttl time.Duration) collectors.DeltaCounterStore {
+	return NewInMemoryCounterStoreWithOptions(logger, ttl, StoreOptions{})
+}
+
+// NewInMemoryCounterStoreWithOptions returns an in-memory CounterStore configured by opts.
+func NewInMemoryCounterStoreWithOptions(logger *slog.Logger, ttl time.Duration, opts StoreOptions) collectors.DeltaCounterStore {
 	return &InMemoryCounterStore{
-		store:  &sync.Map{},
-		logger: logger,
-		ttl:    ttl,
+		store:   &sync.Map{},
+		logger:  logger,
+		ttl:     ttl,
+		opts:    opts,
+		lru:     newSeriesLRU(opts.MaxSeries),
+		metrics: newStoreMetrics("counter"),
+	}
+}
+
+func newMetricEntry() *MetricEntry {
+	return &MetricEntry{
+		Collected: map[uint64]*collectors.ConstMetric{},
+		mutex:     &sync.RWMutex{},
+		seeding:   map[uint64]struct{}{},
 	}
 }
 
@@ -52,14 +74,19 @@ func (s *InMemoryCounterStore) Increment(metricDescriptor *monitoring.MetricDesc
 		return
 	}
 
-	tmp, _ := s.store.LoadOrStore(metricDescriptor.Name, &MetricEntry{
-		Collected: map[uint64]*collectors.ConstMetric{},
-		mutex:     &sync.RWMutex{},
-	})
+	tmp, _ := s.store.LoadOrStore(metricDescriptor.Name, newMetricEntry())
 	entry := tmp.(*MetricEntry)
 
 	key := toCounterKey(currentValue)
+	s.increment(entry, key, currentValue)
+
+	// Series are evicted once the entry is unlocked, as they may belong to it.
+	for _, victim := range s.lru.touch(seriesRef{descriptor: metricDescriptor.Name, key: key}) {
+		s.evict(victim)
+	}
+}
 
+func (s *InMemoryCounterStore) increment(entry *MetricEntry, key uint64, currentValue *collectors.ConstMetric) {
 	entry.mutex.Lock()
 	defer entry.mutex.Unlock()
 	existing := entry.Collected[key]
@@ -67,12 +94,16 @@ func (s *InMemoryCounterStore) Increment(metricDescriptor *monitoring.MetricDesc
 	if existing == nil {
 		s.logger.Debug("Tracking new counter", "fqName", currentValue.FqName, "key", key, "current_value", currentValue.Value, "incoming_time", currentValue.ReportTime)
 		entry.Collected[key] = currentValue
+		if s.opts.ZeroSeed {
+			entry.seeding[key] = struct{}{}
+		}
 		return
 	}
 
 	if existing.ReportTime.Before(currentValue.ReportTime) {
 		s.logger.Debug("Incrementing existing counter", "fqName", currentValue.FqName, "key", key, "current_value", existing.Value, "adding", currentValue.Value, "last_reported_time", existing.ReportTime, "incoming_time", currentValue.ReportTime)
 		currentValue.Value = currentValue.Value + existing.Value
+		currentValue.StartTime = existing.StartTime
 		entry.Collected[key] = currentValue
 		return
 	}
@@ -80,6 +111,81 @@ func (s *InMemoryCounterStore) Increment(metricDescriptor *monitoring.MetricDesc
 	s.logger.Debug("Ignoring old sample for counter", "fqName", currentValue.FqName, "key", key, "last_reported_time", existing.ReportTime, "incoming_time", currentValue.ReportTime)
 }
 
+// evict stops tracking a series to stay within the series limit.
+func (s *InMemoryCounterStore) evict(ref seriesRef) {
+	tmp, ok := s.store.Load(ref.descriptor)
+	if !ok {
+		return
+	}
+	entry := tmp.(*MetricEntry)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if collected, ok := entry.Collected[ref.key]; ok {
+		s.logger.Debug("Evicting least recently used counter", "key", ref.key, "fqName", collected.FqName)
+		delete(entry.Collected, ref.key)
+		delete(entry.seeding, ref.key)
+		s.metrics.evicted(ref.descriptor)
+	}
+}
+
+// Sweep removes the counters of every descriptor that are outside the TTL.
+func (s *InMemoryCounterStore) Sweep() {
+	ttlWindowStart := time.Now().Add(-s.ttl)
+	s.store.Range(func(name, tmp any) bool {
+		entry := tmp.(*MetricEntry)
+		entry.mutex.Lock()
+		defer entry.mutex.Unlock()
+		for key, collected := range entry.Collected {
+			if ttlWindowStart.After(collected.CollectionTime) {
+				s.logger.Debug("Sweeping counter entry outside of TTL", "key", key, "fqName", collected.FqName)
+				s.forget(entry, name.(string), key)
+			}
+		}
+		return true
+	})
+}
+
+// forget removes a series from a locked entry.
+func (s *InMemoryCounterStore) forget(entry *MetricEntry, descriptor string, key uint64) {
+	delete(entry.Collected, key)
+	delete(entry.seeding, key)
+	s.lru.remove(seriesRef{descriptor: descriptor, key: key})
+}
+
+// ForgetDescriptors implements collectors.DeltaDescriptorForgetter.
+func (s *InMemoryCounterStore) ForgetDescriptors(prefix string) {
+	s.store.Range(func(name, tmp any) bool {
+		if !strings.HasPrefix(name.(string), prefix) {
+			return true
+		}
+		entry := tmp.(*MetricEntry)
+		entry.mutex.Lock()
+		defer entry.mutex.Unlock()
+		for key := range entry.Collected {
+			s.forget(entry, name.(string), key)
+		}
+		s.store.Delete(name)
+		return true
+	})
+	s.metrics.forget(prefix)
+}
+
+func (s *InMemoryCounterStore) Describe(ch chan<- *prometheus.Desc) {
+	s.metrics.describe(ch)
+}
+
+func (s *InMemoryCounterStore) Collect(ch chan<- prometheus.Metric) {
+	series := make(map[string]int)
+	s.store.Range(func(name, tmp any) bool {
+		entry := tmp.(*MetricEntry)
+		entry.mutex.RLock()
+		defer entry.mutex.RUnlock()
+		series[name.(string)] = len(entry.Collected)
+		return true
+	})
+	s.metrics.collect(ch, series)
+}
+
 func toCounterKey(c *collectors.ConstMetric) uint64 {
 	labels := make(map[string]string)
 	keysCopy := append([]string{}, c.LabelKeys...)
@@ -116,14 +222,61 @@ func (s *InMemoryCounterStore) ListMetrics(metricDescriptorName string) []*colle
 		//Scan and remove metrics which are outside the TTL
 		if ttlWindowStart.After(collected.CollectionTime) {
 			s.logger.Debug("Deleting counter entry outside of TTL", "key", key, "fqName", collected.FqName)
-			delete(entry.Collected, key)
+			s.forget(entry, metricDescriptorName, key)
 			continue
 		}
 
 		//Dereference to create shallow copy
 		metricCopy := *collected
+		if _, ok := entry.seeding[key]; ok {
+			delete(entry.seeding, key)
+			metricCopy.Value = 0
+			metricCopy.ReportTime = seedTime(collected.StartTime, collected.ReportTime)
+		}
 		output = append(output, &metricCopy)
 	}
 
 	return output
 }
+
+// snapshot returns a copy of the tracked counters per metric descriptor name.
+func (s *InMemoryCounterStore) snapshot() map[string][]*collectors.ConstMetric {
+	out := make(map[string][]*collectors.ConstMetric)
+	s.store.Range(func(name, tmp any) bool {
+		entry := tmp.(*MetricEntry)
+		entry.mutex.RLock()
+		defer entry.mutex.RUnlock()
+		for _, collected := range entry.Collected {
+			metricCopy := *collected
+			out[name.(string)] = append(out[name.(string)], &metricCopy)
+		}
+		return true
+	})
+	return out
+}
+
+// restore tracks the given counters, skipping the ones collected before
+// expiredBefore.
+func (s *InMemoryCounterStore) restore(metrics map[string][]*collectors.ConstMetric, expiredBefore time.Time) int {
+	restored := 0
+	var evicted []seriesRef
+	for name, collected := range metrics {
+		tmp, _ := s.store.LoadOrStore(name, newMetricEntry())
+		entry := tmp.(*MetricEntry)
+		entry.mutex.Lock()
+		for _, metric := range collected {
+			if expiredBefore.After(metric.CollectionTime) {
+				continue
+			}
+			key := toCounterKey(metric)
+			entry.Collected[key] = metric
+			evicted = append(evicted, s.lru.touch(seriesRef{descriptor: name, key: key})...)
+			restored++
+		}
+		entry.mutex.Unlock()
+	}
+	for _, victim := range evicted {
+		s.evict(victim)
+	}
+	return restored
+}
@@ -73,6 +73,18 @@ var _ = Describe("Counter", func() {
 		Expect(metrics[0].Value).To(Equal(float64(30)))
 	})
 
+	It("forgets the counters of descriptors starting with a prefix", func() {
+		removed := &monitoring.MetricDescriptor{Name: "projects/p/metricDescriptors/custom.googleapis.com/requests"}
+		kept := &monitoring.MetricDescriptor{Name: "projects/p2/metricDescriptors/custom.googleapis.com/requests"}
+		store.Increment(removed, metric)
+		store.Increment(kept, metric)
+
+		store.(collectors.DeltaDescriptorForgetter).ForgetDescriptors("projects/p/metricDescriptors/")
+
+		Expect(store.ListMetrics(removed.Name)).To(BeEmpty())
+		Expect(store.ListMetrics(kept.Name)).To(HaveLen(1))
+	})
+
 	It("will remove counters outside of TTL", func() {
 		metric.CollectionTime = metric.CollectionTime.Add(-time.Hour)
 
@@ -81,4 +93,20 @@ var _ = Describe("Counter", func() {
 		metrics := store.ListMetrics(descriptor.Name)
 		Expect(len(metrics)).To(Equal(0))
 	})
+
+	It("reports a zero sample before the first value when zero seeded", func() {
+		store = delta.NewInMemoryCounterStoreWithOptions(promslog.New(&promslog.Config{}), time.Minute, delta.StoreOptions{ZeroSeed: true})
+		metric.StartTime = metric.ReportTime.Add(-time.Minute)
+		store.Increment(descriptor, metric)
+
+		metrics := store.ListMetrics(descriptor.Name)
+		Expect(len(metrics)).To(Equal(1))
+		Expect(metrics[0].Value).To(Equal(float64(0)))
+		Expect(metrics[0].ReportTime).To(Equal(metric.StartTime))
+
+		metrics = store.ListMetrics(descriptor.Name)
+		Expect(len(metrics)).To(Equal(1))
+		Expect(metrics[0].Value).To(Equal(float64(10)))
+		Expect(metrics[0].ReportTime).To(Equal(metric.ReportTime))
+	})
 })
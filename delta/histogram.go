@@ -16,11 +16,13 @@ package delta
 import (
 	"fmt"
 	"log/slog"
+	"maps"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/api/monitoring/v3"
 
 	"github.com/prometheus-community/stackdriver_exporter/collectors"
@@ -30,20 +32,41 @@ import (
 type HistogramEntry struct {
 	Collected map[uint64]*collectors.HistogramMetric
 	mutex     *sync.RWMutex
+	// seeding holds the keys of new histograms whose zero sample has not been listed yet.
+	seeding map[uint64]struct{}
 }
 
 type InMemoryHistogramStore struct {
-	store  *sync.Map
-	ttl    time.Duration
-	logger *slog.Logger
+	store   *sync.Map
+	ttl     time.Duration
+	opts    StoreOptions
+	lru     *seriesLRU
+	metrics *storeMetrics
+	logger  *slog.Logger
 }
 
 // NewInMemoryHistogramStore returns an implementation of HistogramStore which is persisted in-memory
 func NewInMemoryHistogramStore(logger *slog.Logger, ttl time.Duration) collectors.DeltaHistogramStore {
+	return NewInMemoryHistogramStoreWithOptions(logger, ttl, StoreOptions{})
+}
+
+// NewInMemoryHistogramStoreWithOptions returns an in-memory HistogramStore configured by opts.
+func NewInMemoryHistogramStoreWithOptions(logger *slog.Logger, ttl time.Duration, opts StoreOptions) collectors.DeltaHistogramStore {
 	return &InMemoryHistogramStore{
-		store:  &sync.Map{},
-		logger: logger,
-		ttl:    ttl,
+		store:   &sync.Map{},
+		logger:  logger,
+		ttl:     ttl,
+		opts:    opts,
+		lru:     newSeriesLRU(opts.MaxSeries),
+		metrics: newStoreMetrics("histogram"),
+	}
+}
+
+func newHistogramEntry() *HistogramEntry {
+	return &HistogramEntry{
+		Collected: map[uint64]*collectors.HistogramMetric{},
+		mutex:     &sync.RWMutex{},
+		seeding:   map[uint64]struct{}{},
 	}
 }
 
@@ -52,14 +75,19 @@ func (s *InMemoryHistogramStore) Increment(metricDescriptor *monitoring.MetricDe
 		return
 	}
 
-	tmp, _ := s.store.LoadOrStore(metricDescriptor.Name, &HistogramEntry{
-		Collected: map[uint64]*collectors.HistogramMetric{},
-		mutex:     &sync.RWMutex{},
-	})
+	tmp, _ := s.store.LoadOrStore(metricDescriptor.Name, newHistogramEntry())
 	entry := tmp.(*HistogramEntry)
 
 	key := toHistogramKey(currentValue)
+	s.increment(entry, metricDescriptor.Name, key, currentValue)
+
+	// Series are evicted once the entry is unlocked, as they may belong to it.
+	for _, victim := range s.lru.touch(seriesRef{descriptor: metricDescriptor.Name, key: key}) {
+		s.evict(victim)
+	}
+}
 
+func (s *InMemoryHistogramStore) increment(entry *HistogramEntry, descriptor string, key uint64, currentValue *collectors.HistogramMetric) {
 	entry.mutex.Lock()
 	defer entry.mutex.Unlock()
 	existing := entry.Collected[key]
@@ -67,12 +95,38 @@ func (s *InMemoryHistogramStore) Increment(metricDescriptor *monitoring.MetricDe
 	if existing == nil {
 		s.logger.Debug("Tracking new histogram", "fqName", currentValue.FqName, "key", key, "incoming_time", currentValue.ReportTime)
 		entry.Collected[key] = currentValue
+		if s.opts.ZeroSeed {
+			entry.seeding[key] = struct{}{}
+		}
 		return
 	}
 
 	if existing.ReportTime.Before(currentValue.ReportTime) {
+		if !existing.SameLayout(currentValue) {
+			if s.opts.BucketChange != BucketChangeRebucket {
+				s.logger.Debug("Resetting histogram whose bucket bounds changed", "fqName", currentValue.FqName, "key", key)
+				entry.Collected[key] = currentValue
+				s.metrics.reset(descriptor, "bucket_change")
+				return
+			}
+			s.logger.Debug("Re-bucketing histogram onto new bucket bounds", "fqName", currentValue.FqName, "key", key)
+			rebucketed := *existing
+			rebucketed.Rebucket(currentValue.Bounds())
+			existing = &rebucketed
+		}
+
+		incoming := *currentValue
+		incoming.Buckets = maps.Clone(currentValue.Buckets)
+
 		s.logger.Debug("Incrementing existing histogram", "fqName", currentValue.FqName, "key", key, "last_reported_time", existing.ReportTime, "incoming_time", currentValue.ReportTime)
 		currentValue.MergeHistogram(existing)
+		currentValue.StartTime = existing.StartTime
+		if err := currentValue.Validate(); err != nil {
+			s.logger.Warn("Resetting histogram that is no longer valid after merging", "fqName", currentValue.FqName, "key", key, "err", err)
+			entry.Collected[key] = &incoming
+			s.metrics.reset(descriptor, "invalid")
+			return
+		}
 		// Replace the existing histogram by the new one after merging it.
 		entry.Collected[key] = currentValue
 		return
@@ -81,6 +135,81 @@ func (s *InMemoryHistogramStore) Increment(metricDescriptor *monitoring.MetricDe
 	s.logger.Debug("Ignoring old sample for histogram", "fqName", currentValue.FqName, "key", key, "last_reported_time", existing.ReportTime, "incoming_time", currentValue.ReportTime)
 }
 
+// evict stops tracking a series to stay within the series limit.
+func (s *InMemoryHistogramStore) evict(ref seriesRef) {
+	tmp, ok := s.store.Load(ref.descriptor)
+	if !ok {
+		return
+	}
+	entry := tmp.(*HistogramEntry)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if collected, ok := entry.Collected[ref.key]; ok {
+		s.logger.Debug("Evicting least recently used histogram", "key", ref.key, "fqName", collected.FqName)
+		delete(entry.Collected, ref.key)
+		delete(entry.seeding, ref.key)
+		s.metrics.evicted(ref.descriptor)
+	}
+}
+
+// Sweep removes the histograms of every descriptor that are outside the TTL.
+func (s *InMemoryHistogramStore) Sweep() {
+	ttlWindowStart := time.Now().Add(-s.ttl)
+	s.store.Range(func(name, tmp any) bool {
+		entry := tmp.(*HistogramEntry)
+		entry.mutex.Lock()
+		defer entry.mutex.Unlock()
+		for key, collected := range entry.Collected {
+			if ttlWindowStart.After(collected.CollectionTime) {
+				s.logger.Debug("Sweeping histogram entry outside of TTL", "key", key, "fqName", collected.FqName)
+				s.forget(entry, name.(string), key)
+			}
+		}
+		return true
+	})
+}
+
+// forget removes a series from a locked entry.
+func (s *InMemoryHistogramStore) forget(entry *HistogramEntry, descriptor string, key uint64) {
+	delete(entry.Collected, key)
+	delete(entry.seeding, key)
+	s.lru.remove(seriesRef{descriptor: descriptor, key: key})
+}
+
+// ForgetDescriptors implements collectors.DeltaDescriptorForgetter.
+func (s *InMemoryHistogramStore) ForgetDescriptors(prefix string) {
+	s.store.Range(func(name, tmp any) bool {
+		if !strings.HasPrefix(name.(string), prefix) {
+			return true
+		}
+		entry := tmp.(*HistogramEntry)
+		entry.mutex.Lock()
+		defer entry.mutex.Unlock()
+		for key := range entry.Collected {
+			s.forget(entry, name.(string), key)
+		}
+		s.store.Delete(name)
+		return true
+	})
+	s.metrics.forget(prefix)
+}
+
+func (s *InMemoryHistogramStore) Describe(ch chan<- *prometheus.Desc) {
+	s.metrics.describe(ch)
+}
+
+func (s *InMemoryHistogramStore) Collect(ch chan<- prometheus.Metric) {
+	series := make(map[string]int)
+	s.store.Range(func(name, tmp any) bool {
+		entry := tmp.(*HistogramEntry)
+		entry.mutex.RLock()
+		defer entry.mutex.RUnlock()
+		series[name.(string)] = len(entry.Collected)
+		return true
+	})
+	s.metrics.collect(ch, series)
+}
+
 func toHistogramKey(hist *collectors.HistogramMetric) uint64 {
 	labels := make(map[string]string)
 	keysCopy := append([]string{}, hist.LabelKeys...)
@@ -117,13 +246,66 @@ func (s *InMemoryHistogramStore) ListMetrics(metricDescriptorName string) []*col
 		// Scan and remove metrics which are outside the TTL
 		if ttlWindowStart.After(collected.CollectionTime) {
 			s.logger.Debug("Deleting histogram entry outside of TTL", "key", key, "fqName", collected.FqName)
-			delete(entry.Collected, key)
+			s.forget(entry, metricDescriptorName, key)
 			continue
 		}
 
 		copy := *collected
+		if _, ok := entry.seeding[key]; ok {
+			delete(entry.seeding, key)
+			copy.Sum = 0
+			copy.Count = 0
+			copy.Buckets = make(map[float64]uint64, len(collected.Buckets))
+			for bound := range collected.Buckets {
+				copy.Buckets[bound] = 0
+			}
+			copy.ReportTime = seedTime(collected.StartTime, collected.ReportTime)
+		}
 		output = append(output, &copy)
 	}
 
 	return output
 }
+
+// snapshot returns a copy of the tracked histograms per metric descriptor name.
+func (s *InMemoryHistogramStore) snapshot() map[string][]*collectors.HistogramMetric {
+	out := make(map[string][]*collectors.HistogramMetric)
+	s.store.Range(func(name, tmp any) bool {
+		entry := tmp.(*HistogramEntry)
+		entry.mutex.RLock()
+		defer entry.mutex.RUnlock()
+		for _, collected := range entry.Collected {
+			histogramCopy := *collected
+			histogramCopy.Buckets = maps.Clone(collected.Buckets)
+			out[name.(string)] = append(out[name.(string)], &histogramCopy)
+		}
+		return true
+	})
+	return out
+}
+
+// restore tracks the given histograms, skipping the ones collected before
+// expiredBefore.
+func (s *InMemoryHistogramStore) restore(metrics map[string][]*collectors.HistogramMetric, expiredBefore time.Time) int {
+	restored := 0
+	var evicted []seriesRef
+	for name, collected := range metrics {
+		tmp, _ := s.store.LoadOrStore(name, newHistogramEntry())
+		entry := tmp.(*HistogramEntry)
+		entry.mutex.Lock()
+		for _, metric := range collected {
+			if expiredBefore.After(metric.CollectionTime) {
+				continue
+			}
+			key := toHistogramKey(metric)
+			entry.Collected[key] = metric
+			evicted = append(evicted, s.lru.touch(seriesRef{descriptor: name, key: key})...)
+			restored++
+		}
+		entry.mutex.Unlock()
+	}
+	for _, victim := range evicted {
+		s.evict(victim)
+	}
+	return restored
+}
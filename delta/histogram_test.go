@@ -14,10 +14,14 @@
 package delta_test
 
 import (
+	"math"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/promslog"
 	"google.golang.org/api/monitoring/v3"
 
@@ -91,4 +95,70 @@ var _ = Describe("HistogramStore", func() {
 		metrics := store.ListMetrics(descriptor.Name)
 		Expect(len(metrics)).To(Equal(0))
 	})
+
+	It("reports an empty histogram before the first value when zero seeded", func() {
+		store = delta.NewInMemoryHistogramStoreWithOptions(promslog.New(&promslog.Config{}), time.Minute, delta.StoreOptions{ZeroSeed: true})
+		store.Increment(descriptor, histogram)
+
+		metrics := store.ListMetrics(descriptor.Name)
+		Expect(len(metrics)).To(Equal(1))
+		Expect(metrics[0].Count).To(Equal(uint64(0)))
+		Expect(metrics[0].Sum).To(Equal(0.0))
+		Expect(metrics[0].Buckets).To(Equal(map[float64]uint64{bucketKey: 0}))
+		// Without a start time the zero sample is reported just before the first point ends.
+		Expect(metrics[0].ReportTime).To(Equal(histogram.ReportTime.Add(-time.Millisecond)))
+
+		metrics = store.ListMetrics(descriptor.Name)
+		Expect(len(metrics)).To(Equal(1))
+		Expect(metrics[0].Count).To(Equal(uint64(100)))
+		Expect(metrics[0].Buckets[bucketKey]).To(Equal(bucketValue))
+	})
+
+	Context("when the bucket bounds change", func() {
+		var changed *collectors.HistogramMetric
+
+		BeforeEach(func() {
+			histogram.Count = 4
+			histogram.Buckets = map[float64]uint64{10: 2, 20: 4, math.Inf(1): 4}
+			changed = &collectors.HistogramMetric{
+				FqName:         "histogram_name",
+				LabelKeys:      []string{"labelKey"},
+				Sum:            10,
+				Count:          2,
+				Buckets:        map[float64]uint64{15: 1, math.Inf(1): 2},
+				LabelValues:    []string{"labelValue"},
+				ReportTime:     histogram.ReportTime.Add(time.Second),
+				CollectionTime: histogram.CollectionTime,
+				KeysHash:       8765,
+			}
+		})
+
+		It("starts over by default", func() {
+			store.Increment(descriptor, histogram)
+			store.Increment(descriptor, changed)
+
+			metrics := store.ListMetrics(descriptor.Name)
+			Expect(len(metrics)).To(Equal(1))
+			Expect(metrics[0].Count).To(Equal(uint64(2)))
+			Expect(metrics[0].Buckets).To(Equal(map[float64]uint64{15: 1, math.Inf(1): 2}))
+
+			expected := `
+# HELP stackdriver_exporter_delta_store_resets_total Total number of aggregated DELTA series started over per metric descriptor, because their bucket bounds changed or they were no longer valid.
+# TYPE stackdriver_exporter_delta_store_resets_total counter
+stackdriver_exporter_delta_store_resets_total{descriptor="This is a metric",reason="bucket_change",store="histogram"} 1
+`
+			Expect(testutil.CollectAndCompare(store.(prometheus.Collector), strings.NewReader(expected), "stackdriver_exporter_delta_store_resets_total")).To(Succeed())
+		})
+
+		It("re-buckets the accumulated histogram onto the new bounds", func() {
+			store = delta.NewInMemoryHistogramStoreWithOptions(promslog.New(&promslog.Config{}), time.Minute, delta.StoreOptions{BucketChange: delta.BucketChangeRebucket})
+			store.Increment(descriptor, histogram)
+			store.Increment(descriptor, changed)
+
+			metrics := store.ListMetrics(descriptor.Name)
+			Expect(len(metrics)).To(Equal(1))
+			Expect(metrics[0].Count).To(Equal(uint64(6)))
+			Expect(metrics[0].Buckets).To(Equal(map[float64]uint64{15: 4, math.Inf(1): 6}))
+		})
+	})
 })
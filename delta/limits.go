@@ -0,0 +1,193 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delta
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// seriesRef identifies a series tracked by a store.
+type seriesRef struct {
+	descriptor string
+	key        uint64
+}
+
+// seriesLRU orders the series of a store by their last increment. A nil
+// seriesLRU tracks nothing and never evicts.
+type seriesLRU struct {
+	lock     sync.Mutex
+	max      int
+	order    *list.List
+	elements map[seriesRef]*list.Element
+}
+
+func newSeriesLRU(max int) *seriesLRU {
+	if max <= 0 {
+		return nil
+	}
+	return &seriesLRU{max: max, order: list.New(), elements: make(map[seriesRef]*list.Element)}
+}
+
+// touch marks ref as the most recently used series and returns the least
+// recently used series to evict to stay within the limit.
+func (l *seriesLRU) touch(ref seriesRef) []seriesRef {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if element, ok := l.elements[ref]; ok {
+		l.order.MoveToFront(element)
+		return nil
+	}
+	l.elements[ref] = l.order.PushFront(ref)
+
+	var evicted []seriesRef
+	for l.order.Len() > l.max {
+		oldest := l.order.Back()
+		victim := l.order.Remove(oldest).(seriesRef)
+		delete(l.elements, victim)
+		evicted = append(evicted, victim)
+	}
+	return evicted
+}
+
+func (l *seriesLRU) remove(ref seriesRef) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if element, ok := l.elements[ref]; ok {
+		l.order.Remove(element)
+		delete(l.elements, ref)
+	}
+}
+
+// storeMetrics describes the series tracked and evicted by a store.
+type storeMetrics struct {
+	store         string
+	seriesDesc    *prometheus.Desc
+	evictionsDesc *prometheus.Desc
+	resetsDesc    *prometheus.Desc
+
+	lock      sync.Mutex
+	evictions map[string]float64
+	resets    map[[2]string]float64
+}
+
+func newStoreMetrics(store string) *storeMetrics {
+	return &storeMetrics{
+		store: store,
+		seriesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("stackdriver_exporter", "delta_store", "series"),
+			"Number of aggregated DELTA series tracked per metric descriptor.",
+			[]string{"store", "descriptor"}, nil,
+		),
+		evictionsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("stackdriver_exporter", "delta_store", "evictions_total"),
+			"Total number of aggregated DELTA series evicted per metric descriptor to stay within the series limit.",
+			[]string{"store", "descriptor"}, nil,
+		),
+		resetsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("stackdriver_exporter", "delta_store", "resets_total"),
+			"Total number of aggregated DELTA series started over per metric descriptor, because their bucket bounds changed or they were no longer valid.",
+			[]string{"store", "descriptor", "reason"}, nil,
+		),
+		evictions: make(map[string]float64),
+		resets:    make(map[[2]string]float64),
+	}
+}
+
+func (m *storeMetrics) evicted(descriptor string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.evictions[descriptor]++
+}
+
+func (m *storeMetrics) reset(descriptor, reason string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.resets[[2]string{descriptor, reason}]++
+}
+
+// forget drops the evictions and resets of the descriptors whose name starts
+// with prefix.
+func (m *storeMetrics) forget(prefix string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for descriptor := range m.evictions {
+		if strings.HasPrefix(descriptor, prefix) {
+			delete(m.evictions, descriptor)
+		}
+	}
+	for key := range m.resets {
+		if strings.HasPrefix(key[0], prefix) {
+			delete(m.resets, key)
+		}
+	}
+}
+
+func (m *storeMetrics) describe(ch chan<- *prometheus.Desc) {
+	ch <- m.seriesDesc
+	ch <- m.evictionsDesc
+	ch <- m.resetsDesc
+}
+
+// collect reports the number of series of each descriptor that has any, and
+// the evictions and resets of every descriptor.
+func (m *storeMetrics) collect(ch chan<- prometheus.Metric, series map[string]int) {
+	for descriptor, count := range series {
+		if count > 0 {
+			ch <- prometheus.MustNewConstMetric(m.seriesDesc, prometheus.GaugeValue, float64(count), m.store, descriptor)
+		}
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for descriptor, evictions := range m.evictions {
+		ch <- prometheus.MustNewConstMetric(m.evictionsDesc, prometheus.CounterValue, evictions, m.store, descriptor)
+	}
+	for key, resets := range m.resets {
+		ch <- prometheus.MustNewConstMetric(m.resetsDesc, prometheus.CounterValue, resets, m.store, key[0], key[1])
+	}
+}
+
+// Sweeper is a store whose expired series can be removed in the background,
+// including those of descriptors that are no longer scraped.
+type Sweeper interface {
+	Sweep()
+}
+
+// RunSweeper sweeps stores every interval until ctx is done.
+func RunSweeper(ctx context.Context, interval time.Duration, stores ...Sweeper) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, store := range stores {
+				store.Sweep()
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delta_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/collectors"
+	"github.com/prometheus-community/stackdriver_exporter/delta"
+)
+
+var _ = Describe("Store limits", func() {
+	logger := promslog.New(&promslog.Config{})
+	descriptor := &monitoring.MetricDescriptor{Name: "projects/p/metricDescriptors/custom.googleapis.com/requests"}
+
+	newCounter := func(instance string, collectionTime time.Time) *collectors.ConstMetric {
+		return &collectors.ConstMetric{
+			FqName:         "counter_name",
+			LabelKeys:      []string{"instance"},
+			ValueType:      1,
+			Value:          1,
+			LabelValues:    []string{instance},
+			ReportTime:     time.Now(),
+			CollectionTime: collectionTime,
+		}
+	}
+
+	newHistogram := func(instance string, collectionTime time.Time) *collectors.HistogramMetric {
+		return &collectors.HistogramMetric{
+			FqName:         "histogram_name",
+			LabelKeys:      []string{"instance"},
+			Count:          1,
+			Buckets:        map[float64]uint64{1: 1},
+			LabelValues:    []string{instance},
+			ReportTime:     time.Now(),
+			CollectionTime: collectionTime,
+		}
+	}
+
+	instances := func(metrics []*collectors.ConstMetric) []string {
+		var out []string
+		for _, m := range metrics {
+			out = append(out, m.LabelValues[0])
+		}
+		return out
+	}
+
+	It("evicts the least recently incremented counter", func() {
+		store := delta.NewInMemoryCounterStoreWithOptions(logger, time.Hour, delta.StoreOptions{MaxSeries: 2}).(*delta.InMemoryCounterStore)
+		now := time.Now()
+		store.Increment(descriptor, newCounter("a", now))
+		store.Increment(descriptor, newCounter("b", now))
+		store.Increment(descriptor, newCounter("a", now))
+		store.Increment(descriptor, newCounter("c", now))
+
+		Expect(instances(store.ListMetrics(descriptor.Name))).To(ConsistOf("a", "c"))
+
+		expected := fmt.Sprintf(`
+# HELP stackdriver_exporter_delta_store_evictions_total Total number of aggregated DELTA series evicted per metric descriptor to stay within the series limit.
+# TYPE stackdriver_exporter_delta_store_evictions_total counter
+stackdriver_exporter_delta_store_evictions_total{descriptor=%[1]q,store="counter"} 1
+# HELP stackdriver_exporter_delta_store_series Number of aggregated DELTA series tracked per metric descriptor.
+# TYPE stackdriver_exporter_delta_store_series gauge
+stackdriver_exporter_delta_store_series{descriptor=%[1]q,store="counter"} 2
+`, descriptor.Name)
+		Expect(testutil.CollectAndCompare(store, strings.NewReader(expected))).To(Succeed())
+	})
+
+	It("evicts the least recently incremented histogram", func() {
+		store := delta.NewInMemoryHistogramStoreWithOptions(logger, time.Hour, delta.StoreOptions{MaxSeries: 1})
+		now := time.Now()
+		store.Increment(descriptor, newHistogram("a", now))
+		store.Increment(descriptor, newHistogram("b", now))
+
+		histograms := store.ListMetrics(descriptor.Name)
+		Expect(histograms).To(HaveLen(1))
+		Expect(histograms[0].LabelValues).To(Equal([]string{"b"}))
+	})
+
+	It("sweeps series outside of TTL of descriptors that are not listed", func() {
+		store := delta.NewInMemoryCounterStoreWithOptions(logger, time.Minute, delta.StoreOptions{MaxSeries: 2}).(*delta.InMemoryCounterStore)
+		now := time.Now()
+		store.Increment(descriptor, newCounter("expired", now.Add(-time.Hour)))
+		store.Increment(descriptor, newCounter("a", now))
+
+		store.Sweep()
+		Expect(testutil.ToFloat64(store)).To(Equal(float64(1)))
+
+		// The swept series no longer counts against the limit.
+		store.Increment(descriptor, newCounter("b", now))
+		Expect(instances(store.ListMetrics(descriptor.Name))).To(ConsistOf("a", "b"))
+	})
+})
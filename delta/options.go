@@ -0,0 +1,49 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delta
+
+import (
+	"time"
+)
+
+// StoreOptions configures the in-memory delta stores.
+type StoreOptions struct {
+	// ZeroSeed makes the first listing of a new series report a zero sample at the start of the interval of its
+	// first point, and the following listings its accumulated value. Without it, the first point of a series is
+	// reported as the value the counter starts at, and rate() misses it.
+	ZeroSeed bool
+	// MaxSeries is the maximum number of series tracked by a store, 0 for unlimited. When a new series would exceed
+	// it, the series incremented least recently is evicted.
+	MaxSeries int
+	// BucketChange is what the histogram store does when the bucket bounds of a series change: BucketChangeReset,
+	// the default, or BucketChangeRebucket.
+	BucketChange string
+}
+
+const (
+	// BucketChangeReset starts a histogram over from the point whose bucket bounds changed.
+	BucketChangeReset = "reset"
+	// BucketChangeRebucket interpolates the accumulated histogram onto the new bucket bounds before adding the point.
+	BucketChangeRebucket = "rebucket"
+)
+
+// seedTime returns the timestamp of the zero sample of a new series: the
+// start of the interval of its first point, or just before the point ends
+// when the start is unknown.
+func seedTime(startTime, reportTime time.Time) time.Time {
+	if startTime.IsZero() || !startTime.Before(reportTime) {
+		return reportTime.Add(-time.Millisecond)
+	}
+	return startTime
+}
@@ -0,0 +1,190 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delta
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/prometheus-community/stackdriver_exporter/collectors"
+)
+
+// Snapshot holds the state of the delta stores per metric descriptor name.
+type Snapshot struct {
+	Counters   map[string][]*collectors.ConstMetric
+	Histograms map[string][]*collectors.HistogramMetric
+}
+
+// SnapshotBackend persists snapshots of the delta stores.
+type SnapshotBackend interface {
+	// Load returns the last saved snapshot, or an empty one if none was saved.
+	Load() (*Snapshot, error)
+	// Save replaces the saved snapshot.
+	Save(snapshot *Snapshot) error
+	Close() error
+}
+
+// PersistentStore keeps the aggregated DELTA counters and histograms of every
+// collector in memory and saves them to a SnapshotBackend, so they survive
+// restarts. Entries are keyed by metric descriptor name, which includes the
+// project, so a single store is shared by all collectors.
+type PersistentStore struct {
+	counters   *InMemoryCounterStore
+	histograms *InMemoryHistogramStore
+	backend    SnapshotBackend
+	logger     *slog.Logger
+
+	// lock serializes snapshots.
+	lock sync.Mutex
+}
+
+// NewPersistentStore restores the last snapshot saved to backend, dropping
+// the entries collected more than ttl ago. Restored series are not zero seeded.
+func NewPersistentStore(logger *slog.Logger, backend SnapshotBackend, ttl time.Duration, opts StoreOptions) (*PersistentStore, error) {
+	s := &PersistentStore{
+		counters:   NewInMemoryCounterStoreWithOptions(logger, ttl, opts).(*InMemoryCounterStore),
+		histograms: NewInMemoryHistogramStoreWithOptions(logger, ttl, opts).(*InMemoryHistogramStore),
+		backend:    backend,
+		logger:     logger,
+	}
+
+	snapshot, err := backend.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error loading delta store snapshot: %w", err)
+	}
+	expiredBefore := time.Now().Add(-ttl)
+	counters := s.counters.restore(snapshot.Counters, expiredBefore)
+	histograms := s.histograms.restore(snapshot.Histograms, expiredBefore)
+	logger.Info("restored delta store snapshot", "counters", counters, "histograms", histograms)
+	return s, nil
+}
+
+// CounterStoreFactory implements collectors.CounterStoreFactory, returning
+// the shared counter store.
+func (s *PersistentStore) CounterStoreFactory(*slog.Logger, time.Duration) collectors.DeltaCounterStore {
+	return s.counters
+}
+
+// HistogramStoreFactory implements collectors.HistogramStoreFactory,
+// returning the shared histogram store.
+func (s *PersistentStore) HistogramStoreFactory(*slog.Logger, time.Duration) collectors.DeltaHistogramStore {
+	return s.histograms
+}
+
+// Sweep removes the counters and histograms outside the TTL.
+func (s *PersistentStore) Sweep() {
+	s.counters.Sweep()
+	s.histograms.Sweep()
+}
+
+func (s *PersistentStore) Describe(ch chan<- *prometheus.Desc) {
+	s.counters.Describe(ch)
+	s.histograms.Describe(ch)
+}
+
+func (s *PersistentStore) Collect(ch chan<- prometheus.Metric) {
+	s.counters.Collect(ch)
+	s.histograms.Collect(ch)
+}
+
+// Snapshot saves the current state to the backend.
+func (s *PersistentStore) Snapshot() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.backend.Save(&Snapshot{
+		Counters:   s.counters.snapshot(),
+		Histograms: s.histograms.snapshot(),
+	})
+}
+
+// Run saves a snapshot every interval until ctx is done.
+func (s *PersistentStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Snapshot(); err != nil {
+				s.logger.Error("error saving delta store snapshot", "err", err)
+			}
+		}
+	}
+}
+
+// Close saves a last snapshot and closes the backend.
+func (s *PersistentStore) Close() error {
+	return errors.Join(s.Snapshot(), s.backend.Close())
+}
+
+// FileBackend saves snapshots to a single file, replaced atomically.
+type FileBackend struct {
+	path string
+}
+
+// NewFileBackend returns a backend saving snapshots to path.
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{path: path}
+}
+
+func (b *FileBackend) Load() (*Snapshot, error) {
+	content, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", b.path, err)
+	}
+	return snapshot, nil
+}
+
+func (b *FileBackend) Save(snapshot *Snapshot) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
+
+func (b *FileBackend) Close() error {
+	return nil
+}
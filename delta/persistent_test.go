@@ -0,0 +1,128 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delta_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/common/promslog"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/collectors"
+	"github.com/prometheus-community/stackdriver_exporter/delta"
+)
+
+var _ = Describe("PersistentStore", func() {
+	logger := promslog.New(&promslog.Config{})
+	descriptor := &monitoring.MetricDescriptor{Name: "projects/p/metricDescriptors/custom.googleapis.com/requests"}
+
+	var dir string
+	var counter *collectors.ConstMetric
+	var histogram *collectors.HistogramMetric
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "delta-store")
+		Expect(err).NotTo(HaveOccurred())
+
+		now := time.Now().Truncate(time.Second)
+		counter = &collectors.ConstMetric{
+			FqName:         "counter_name",
+			LabelKeys:      []string{"labelKey"},
+			ValueType:      1,
+			Value:          10,
+			LabelValues:    []string{"labelValue"},
+			ReportTime:     now,
+			CollectionTime: now,
+			KeysHash:       4321,
+		}
+		histogram = &collectors.HistogramMetric{
+			FqName:         "histogram_name",
+			LabelKeys:      []string{"labelKey"},
+			Sum:            10,
+			Count:          3,
+			Buckets:        map[float64]uint64{1: 1, math.Inf(1): 3},
+			LabelValues:    []string{"labelValue"},
+			ReportTime:     now,
+			CollectionTime: now,
+			KeysHash:       8765,
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	backends := map[string]func(path string) delta.SnapshotBackend{
+		"file": func(path string) delta.SnapshotBackend { return delta.NewFileBackend(path) },
+		"bolt": func(path string) delta.SnapshotBackend {
+			b, err := delta.NewBoltBackend(path)
+			Expect(err).NotTo(HaveOccurred())
+			return b
+		},
+	}
+
+	for name, newBackend := range backends {
+		Context("with a "+name+" backend", func() {
+			It("restores tracked counters and histograms after a restart", func() {
+				path := filepath.Join(dir, "store")
+				store, err := delta.NewPersistentStore(logger, newBackend(path), time.Hour, delta.StoreOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				store.CounterStoreFactory(logger, time.Hour).Increment(descriptor, counter)
+				store.HistogramStoreFactory(logger, time.Hour).Increment(descriptor, histogram)
+				Expect(store.Close()).To(Succeed())
+
+				restored, err := delta.NewPersistentStore(logger, newBackend(path), time.Hour, delta.StoreOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				defer restored.Close()
+
+				counters := restored.CounterStoreFactory(logger, time.Hour).ListMetrics(descriptor.Name)
+				Expect(counters).To(HaveLen(1))
+				Expect(counters[0].Value).To(Equal(float64(10)))
+				Expect(counters[0].ReportTime.Equal(counter.ReportTime)).To(BeTrue())
+
+				histograms := restored.HistogramStoreFactory(logger, time.Hour).ListMetrics(descriptor.Name)
+				Expect(histograms).To(HaveLen(1))
+				Expect(histograms[0].Buckets).To(Equal(histogram.Buckets))
+			})
+
+			It("drops entries outside of TTL on startup", func() {
+				path := filepath.Join(dir, "store")
+				counter.CollectionTime = counter.CollectionTime.Add(-2 * time.Hour)
+
+				store, err := delta.NewPersistentStore(logger, newBackend(path), 3*time.Hour, delta.StoreOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				store.CounterStoreFactory(logger, time.Hour).Increment(descriptor, counter)
+				Expect(store.Close()).To(Succeed())
+
+				restored, err := delta.NewPersistentStore(logger, newBackend(path), time.Hour, delta.StoreOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				defer restored.Close()
+				Expect(restored.CounterStoreFactory(logger, time.Hour).ListMetrics(descriptor.Name)).To(BeEmpty())
+			})
+
+			It("starts empty without a snapshot", func() {
+				store, err := delta.NewPersistentStore(logger, newBackend(filepath.Join(dir, "missing")), time.Hour, delta.StoreOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				defer store.Close()
+				Expect(store.CounterStoreFactory(logger, time.Hour).ListMetrics(descriptor.Name)).To(BeEmpty())
+			})
+		})
+	}
+})
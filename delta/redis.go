@@ -0,0 +1,387 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delta
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/collectors"
+)
+
+// Times are stored as Unix microseconds, which Lua numbers represent exactly.
+// Infinite bucket bounds are stored as "+Inf", which strconv.ParseFloat reads back.
+
+// incrementCounterScript adds a delta to a counter unless a point with the
+// same or a later end time was already added.
+//
+// KEYS: series hash, descriptor index set.
+// ARGV: series member, report time, collection time, value, meta, TTL in milliseconds.
+var incrementCounterScript = redis.NewScript(`
+local last = tonumber(redis.call('HGET', KEYS[1], 'report_time') or '0')
+if tonumber(ARGV[2]) <= last then
+  return 0
+end
+redis.call('HINCRBYFLOAT', KEYS[1], 'value', ARGV[4])
+redis.call('HSET', KEYS[1], 'report_time', ARGV[2], 'collection_time', ARGV[3], 'meta', ARGV[5])
+redis.call('PEXPIRE', KEYS[1], ARGV[6])
+redis.call('SADD', KEYS[2], ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[6])
+return 1
+`)
+
+// incrementHistogramScript merges a delta into a histogram unless a point
+// with the same or a later end time was already merged. A histogram whose
+// bucket layout changed starts over from the point.
+//
+// KEYS: series hash, descriptor index set.
+// ARGV: series member, report time, collection time, sum, count, meta, TTL in milliseconds, layout,
+// followed by bucket field and count pairs.
+var incrementHistogramScript = redis.NewScript(`
+local last = tonumber(redis.call('HGET', KEYS[1], 'report_time') or '0')
+if tonumber(ARGV[2]) <= last then
+  return 0
+end
+local result = 1
+local layout = redis.call('HGET', KEYS[1], 'layout')
+if layout and layout ~= ARGV[8] then
+  redis.call('DEL', KEYS[1])
+  result = 2
+end
+redis.call('HINCRBYFLOAT', KEYS[1], 'sum', ARGV[4])
+redis.call('HINCRBY', KEYS[1], 'count', ARGV[5])
+for i = 9, #ARGV, 2 do
+  redis.call('HINCRBY', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+redis.call('HSET', KEYS[1], 'report_time', ARGV[2], 'collection_time', ARGV[3], 'meta', ARGV[6], 'layout', ARGV[8])
+redis.call('PEXPIRE', KEYS[1], ARGV[7])
+redis.call('SADD', KEYS[2], ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[7])
+return result
+`)
+
+const bucketFieldPrefix = "b:"
+
+// seriesMeta identifies the series stored in a Redis hash.
+type seriesMeta struct {
+	FqName      string               `json:"fq_name"`
+	LabelKeys   []string             `json:"label_keys"`
+	LabelValues []string             `json:"label_values"`
+	ValueType   prometheus.ValueType `json:"value_type,omitempty"`
+	KeysHash    uint64               `json:"keys_hash"`
+}
+
+// redisStore holds what counter and histogram stores share.
+type redisStore struct {
+	client redis.UniversalClient
+	prefix string
+	kind   string
+	ttl    time.Duration
+	logger *slog.Logger
+}
+
+func (s *redisStore) indexKey(metricDescriptorName string) string {
+	return s.prefix + s.kind + ":" + metricDescriptorName
+}
+
+func (s *redisStore) seriesKey(metricDescriptorName, member string) string {
+	return s.prefix + s.kind + ":" + metricDescriptorName + ":" + member
+}
+
+// list returns the hashes of the series of a descriptor that are within the
+// TTL, and forgets the series whose hash has expired.
+func (s *redisStore) list(ctx context.Context, metricDescriptorName string) []map[string]string {
+	indexKey := s.indexKey(metricDescriptorName)
+	members, err := s.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		s.logger.Error("error listing delta series from redis", "descriptor", metricDescriptorName, "err", err)
+		return nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(members))
+	for i, member := range members {
+		cmds[i] = pipe.HGetAll(ctx, s.seriesKey(metricDescriptorName, member))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Error("error reading delta series from redis", "descriptor", metricDescriptorName, "err", err)
+		return nil
+	}
+
+	ttlWindowStart := time.Now().Add(-s.ttl)
+	var out []map[string]string
+	var expired []interface{}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			expired = append(expired, members[i])
+			continue
+		}
+		if ttlWindowStart.After(fromMicros(fields["collection_time"])) {
+			continue
+		}
+		out = append(out, fields)
+	}
+	if len(expired) > 0 {
+		if err := s.client.SRem(ctx, indexKey, expired...).Err(); err != nil {
+			s.logger.Debug("error forgetting expired delta series", "descriptor", metricDescriptorName, "err", err)
+		}
+	}
+	return out
+}
+
+// ForgetDescriptors implements collectors.DeltaDescriptorForgetter, deleting
+// the keys of the descriptors whose name starts with prefix.
+func (s *redisStore) ForgetDescriptors(prefix string) {
+	ctx := context.Background()
+	iter := s.client.Scan(ctx, 0, s.prefix+s.kind+":"+escapeGlob(prefix)+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		s.logger.Error("error listing delta series to forget from redis", "prefix", prefix, "err", err)
+		return
+	}
+	for chunk := range slices.Chunk(keys, 100) {
+		if err := s.client.Del(ctx, chunk...).Err(); err != nil {
+			s.logger.Error("error forgetting delta series in redis", "prefix", prefix, "err", err)
+			return
+		}
+	}
+}
+
+// escapeGlob escapes the characters of s that are special in a Redis glob
+// pattern.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// RedisCounterStore is a DeltaCounterStore shared by every exporter replica
+// using the same Redis server and key prefix.
+type RedisCounterStore struct {
+	redisStore
+}
+
+// NewRedisCounterStore returns a DeltaCounterStore keeping counters in Redis
+// under keys starting with prefix.
+func NewRedisCounterStore(client redis.UniversalClient, prefix string, logger *slog.Logger, ttl time.Duration) collectors.DeltaCounterStore {
+	return &RedisCounterStore{redisStore{client: client, prefix: prefix, kind: "counter", ttl: ttl, logger: logger}}
+}
+
+func (s *RedisCounterStore) Increment(metricDescriptor *monitoring.MetricDescriptor, currentValue *collectors.ConstMetric) {
+	if currentValue == nil {
+		return
+	}
+	meta, err := json.Marshal(seriesMeta{
+		FqName:      currentValue.FqName,
+		LabelKeys:   currentValue.LabelKeys,
+		LabelValues: currentValue.LabelValues,
+		ValueType:   currentValue.ValueType,
+		KeysHash:    currentValue.KeysHash,
+	})
+	if err != nil {
+		s.logger.Error("error encoding counter", "fqName", currentValue.FqName, "err", err)
+		return
+	}
+
+	member := strconv.FormatUint(toCounterKey(currentValue), 10)
+	ctx := context.Background()
+	added, err := incrementCounterScript.Run(ctx, s.client,
+		[]string{s.seriesKey(metricDescriptor.Name, member), s.indexKey(metricDescriptor.Name)},
+		member,
+		currentValue.ReportTime.UnixMicro(),
+		currentValue.CollectionTime.UnixMicro(),
+		strconv.FormatFloat(currentValue.Value, 'g', -1, 64),
+		meta,
+		s.ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		s.logger.Error("error incrementing counter in redis", "fqName", currentValue.FqName, "err", err)
+		return
+	}
+	if added == 0 {
+		s.logger.Debug("Ignoring old sample for counter", "fqName", currentValue.FqName, "key", member, "incoming_time", currentValue.ReportTime)
+	}
+}
+
+func (s *RedisCounterStore) ListMetrics(metricDescriptorName string) []*collectors.ConstMetric {
+	var output []*collectors.ConstMetric
+	for _, fields := range s.list(context.Background(), metricDescriptorName) {
+		var meta seriesMeta
+		if err := json.Unmarshal([]byte(fields["meta"]), &meta); err != nil {
+			s.logger.Error("error decoding counter", "descriptor", metricDescriptorName, "err", err)
+			continue
+		}
+		value, _ := strconv.ParseFloat(fields["value"], 64)
+		output = append(output, &collectors.ConstMetric{
+			FqName:         meta.FqName,
+			LabelKeys:      meta.LabelKeys,
+			ValueType:      meta.ValueType,
+			Value:          value,
+			LabelValues:    meta.LabelValues,
+			ReportTime:     fromMicros(fields["report_time"]),
+			CollectionTime: fromMicros(fields["collection_time"]),
+			KeysHash:       meta.KeysHash,
+		})
+	}
+	return output
+}
+
+// RedisHistogramStore is a DeltaHistogramStore shared by every exporter
+// replica using the same Redis server and key prefix.
+type RedisHistogramStore struct {
+	redisStore
+	metrics *storeMetrics
+}
+
+// NewRedisHistogramStore returns a DeltaHistogramStore keeping histograms in
+// Redis under keys starting with prefix. It is a prometheus.Collector
+// reporting the histograms it reset.
+func NewRedisHistogramStore(client redis.UniversalClient, prefix string, logger *slog.Logger, ttl time.Duration) collectors.DeltaHistogramStore {
+	return &RedisHistogramStore{
+		redisStore: redisStore{client: client, prefix: prefix, kind: "histogram", ttl: ttl, logger: logger},
+		metrics:    newStoreMetrics("histogram"),
+	}
+}
+
+func (s *RedisHistogramStore) Increment(metricDescriptor *monitoring.MetricDescriptor, currentValue *collectors.HistogramMetric) {
+	if currentValue == nil {
+		return
+	}
+	meta, err := json.Marshal(seriesMeta{
+		FqName:      currentValue.FqName,
+		LabelKeys:   currentValue.LabelKeys,
+		LabelValues: currentValue.LabelValues,
+		KeysHash:    currentValue.KeysHash,
+	})
+	if err != nil {
+		s.logger.Error("error encoding histogram", "fqName", currentValue.FqName, "err", err)
+		return
+	}
+
+	member := strconv.FormatUint(toHistogramKey(currentValue), 10)
+	args := []interface{}{
+		member,
+		currentValue.ReportTime.UnixMicro(),
+		currentValue.CollectionTime.UnixMicro(),
+		strconv.FormatFloat(currentValue.Sum, 'g', -1, 64),
+		currentValue.Count,
+		meta,
+		s.ttl.Milliseconds(),
+	}
+	bounds := currentValue.Bounds()
+	layout := make([]string, len(bounds))
+	for i, bound := range bounds {
+		layout[i] = strconv.FormatFloat(bound, 'g', -1, 64)
+	}
+	args = append(args, strings.Join(layout, ","))
+	for i, bound := range bounds {
+		args = append(args, bucketFieldPrefix+layout[i], currentValue.Buckets[bound])
+	}
+
+	ctx := context.Background()
+	added, err := incrementHistogramScript.Run(ctx, s.client,
+		[]string{s.seriesKey(metricDescriptor.Name, member), s.indexKey(metricDescriptor.Name)},
+		args...,
+	).Int()
+	if err != nil {
+		s.logger.Error("error incrementing histogram in redis", "fqName", currentValue.FqName, "err", err)
+		return
+	}
+	switch added {
+	case 0:
+		s.logger.Debug("Ignoring old sample for histogram", "fqName", currentValue.FqName, "key", member, "incoming_time", currentValue.ReportTime)
+	case 2:
+		s.logger.Debug("Resetting histogram whose bucket bounds changed", "fqName", currentValue.FqName, "key", member)
+		s.metrics.reset(metricDescriptor.Name, "bucket_change")
+	}
+}
+
+// ForgetDescriptors implements collectors.DeltaDescriptorForgetter.
+func (s *RedisHistogramStore) ForgetDescriptors(prefix string) {
+	s.redisStore.ForgetDescriptors(prefix)
+	s.metrics.forget(prefix)
+}
+
+func (s *RedisHistogramStore) Describe(ch chan<- *prometheus.Desc) {
+	s.metrics.describe(ch)
+}
+
+// Collect reports the resets of this replica. The series kept in Redis are
+// shared by every replica, so they are not counted.
+func (s *RedisHistogramStore) Collect(ch chan<- prometheus.Metric) {
+	s.metrics.collect(ch, nil)
+}
+
+func (s *RedisHistogramStore) ListMetrics(metricDescriptorName string) []*collectors.HistogramMetric {
+	var output []*collectors.HistogramMetric
+	for _, fields := range s.list(context.Background(), metricDescriptorName) {
+		var meta seriesMeta
+		if err := json.Unmarshal([]byte(fields["meta"]), &meta); err != nil {
+			s.logger.Error("error decoding histogram", "descriptor", metricDescriptorName, "err", err)
+			continue
+		}
+		sum, _ := strconv.ParseFloat(fields["sum"], 64)
+		count, _ := strconv.ParseUint(fields["count"], 10, 64)
+		buckets := make(map[float64]uint64)
+		for field, value := range fields {
+			bound, ok := strings.CutPrefix(field, bucketFieldPrefix)
+			if !ok {
+				continue
+			}
+			upperBound, err := strconv.ParseFloat(bound, 64)
+			if err != nil {
+				continue
+			}
+			buckets[upperBound], _ = strconv.ParseUint(value, 10, 64)
+		}
+		output = append(output, &collectors.HistogramMetric{
+			FqName:         meta.FqName,
+			LabelKeys:      meta.LabelKeys,
+			Sum:            sum,
+			Count:          count,
+			Buckets:        buckets,
+			LabelValues:    meta.LabelValues,
+			ReportTime:     fromMicros(fields["report_time"]),
+			CollectionTime: fromMicros(fields["collection_time"]),
+			KeysHash:       meta.KeysHash,
+		})
+	}
+	return output
+}
+
+func fromMicros(s string) time.Time {
+	micros, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMicro(micros)
+}
@@ -0,0 +1,179 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package delta_test
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/api/monitoring/v3"
+
+	"github.com/prometheus-community/stackdriver_exporter/collectors"
+	"github.com/prometheus-community/stackdriver_exporter/delta"
+)
+
+var _ = Describe("Redis stores", func() {
+	logger := promslog.New(&promslog.Config{})
+	descriptor := &monitoring.MetricDescriptor{Name: "projects/p/metricDescriptors/custom.googleapis.com/requests"}
+	prefix := "test:"
+
+	var server *miniredis.Miniredis
+	var client *redis.Client
+	var now time.Time
+
+	BeforeEach(func() {
+		server = miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		client = redis.NewClient(&redis.Options{Addr: server.Addr()})
+		now = time.Now().Truncate(time.Second)
+	})
+
+	AfterEach(func() {
+		client.Close()
+		server.Close()
+	})
+
+	newCounter := func(value float64, reportTime time.Time) *collectors.ConstMetric {
+		return &collectors.ConstMetric{
+			FqName:         "counter_name",
+			LabelKeys:      []string{"labelKey"},
+			ValueType:      1,
+			Value:          value,
+			LabelValues:    []string{"labelValue"},
+			ReportTime:     reportTime,
+			CollectionTime: now,
+			KeysHash:       4321,
+		}
+	}
+
+	newHistogram := func(reportTime time.Time) *collectors.HistogramMetric {
+		return &collectors.HistogramMetric{
+			FqName:         "histogram_name",
+			LabelKeys:      []string{"labelKey"},
+			Sum:            10,
+			Count:          3,
+			Buckets:        map[float64]uint64{1: 1, math.Inf(1): 3},
+			LabelValues:    []string{"labelValue"},
+			ReportTime:     reportTime,
+			CollectionTime: now,
+			KeysHash:       8765,
+		}
+	}
+
+	Context("RedisCounterStore", func() {
+		It("reports the same value on every replica", func() {
+			first := delta.NewRedisCounterStore(client, prefix, logger, time.Hour)
+			second := delta.NewRedisCounterStore(client, prefix, logger, time.Hour)
+
+			first.Increment(descriptor, newCounter(10, now))
+			second.Increment(descriptor, newCounter(10, now))
+			second.Increment(descriptor, newCounter(5, now.Add(time.Minute)))
+			first.Increment(descriptor, newCounter(5, now.Add(time.Minute)))
+
+			for _, store := range []collectors.DeltaCounterStore{first, second} {
+				metrics := store.ListMetrics(descriptor.Name)
+				Expect(metrics).To(HaveLen(1))
+				Expect(metrics[0].Value).To(Equal(float64(15)))
+				Expect(metrics[0].FqName).To(Equal("counter_name"))
+				Expect(metrics[0].LabelValues).To(Equal([]string{"labelValue"}))
+				Expect(metrics[0].ReportTime.Equal(now.Add(time.Minute))).To(BeTrue())
+			}
+		})
+
+		It("ignores points older than the last one added", func() {
+			store := delta.NewRedisCounterStore(client, prefix, logger, time.Hour)
+			store.Increment(descriptor, newCounter(10, now))
+			store.Increment(descriptor, newCounter(5, now.Add(-time.Minute)))
+
+			metrics := store.ListMetrics(descriptor.Name)
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Value).To(Equal(float64(10)))
+		})
+
+		It("drops series collected outside of TTL", func() {
+			store := delta.NewRedisCounterStore(client, prefix, logger, time.Hour)
+			counter := newCounter(10, now)
+			counter.CollectionTime = now.Add(-2 * time.Hour)
+			store.Increment(descriptor, counter)
+
+			Expect(store.ListMetrics(descriptor.Name)).To(BeEmpty())
+		})
+
+		It("forgets series whose keys expired", func() {
+			store := delta.NewRedisCounterStore(client, prefix, logger, time.Minute)
+			store.Increment(descriptor, newCounter(10, now))
+			server.FastForward(2 * time.Minute)
+
+			Expect(store.ListMetrics(descriptor.Name)).To(BeEmpty())
+		})
+
+		It("deletes the keys of descriptors starting with a prefix", func() {
+			store := delta.NewRedisCounterStore(client, prefix, logger, time.Hour)
+			kept := &monitoring.MetricDescriptor{Name: "projects/p2/metricDescriptors/custom.googleapis.com/requests"}
+			store.Increment(descriptor, newCounter(10, now))
+			store.Increment(kept, newCounter(10, now))
+
+			store.(collectors.DeltaDescriptorForgetter).ForgetDescriptors("projects/p/metricDescriptors/")
+
+			Expect(store.ListMetrics(descriptor.Name)).To(BeEmpty())
+			Expect(store.ListMetrics(kept.Name)).To(HaveLen(1))
+			Expect(server.Keys()).To(HaveLen(2))
+		})
+	})
+
+	Context("RedisHistogramStore", func() {
+		It("merges each point once across replicas", func() {
+			first := delta.NewRedisHistogramStore(client, prefix, logger, time.Hour)
+			second := delta.NewRedisHistogramStore(client, prefix, logger, time.Hour)
+
+			first.Increment(descriptor, newHistogram(now))
+			second.Increment(descriptor, newHistogram(now))
+			second.Increment(descriptor, newHistogram(now.Add(time.Minute)))
+
+			metrics := first.ListMetrics(descriptor.Name)
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Sum).To(Equal(float64(20)))
+			Expect(metrics[0].Count).To(Equal(uint64(6)))
+			Expect(metrics[0].Buckets).To(Equal(map[float64]uint64{1: 2, math.Inf(1): 6}))
+		})
+
+		It("starts over when the bucket bounds change", func() {
+			store := delta.NewRedisHistogramStore(client, prefix, logger, time.Hour)
+			store.Increment(descriptor, newHistogram(now))
+			changed := newHistogram(now.Add(time.Minute))
+			changed.Buckets = map[float64]uint64{2: 2, math.Inf(1): 3}
+			store.Increment(descriptor, changed)
+
+			metrics := store.ListMetrics(descriptor.Name)
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics[0].Count).To(Equal(uint64(3)))
+			Expect(metrics[0].Buckets).To(Equal(map[float64]uint64{2: 2, math.Inf(1): 3}))
+
+			expected := `
+# HELP stackdriver_exporter_delta_store_resets_total Total number of aggregated DELTA series started over per metric descriptor, because their bucket bounds changed or they were no longer valid.
+# TYPE stackdriver_exporter_delta_store_resets_total counter
+stackdriver_exporter_delta_store_resets_total{descriptor="projects/p/metricDescriptors/custom.googleapis.com/requests",reason="bucket_change",store="histogram"} 1
+`
+			Expect(testutil.CollectAndCompare(store.(prometheus.Collector), strings.NewReader(expected), "stackdriver_exporter_delta_store_resets_total")).To(Succeed())
+		})
+	})
+})
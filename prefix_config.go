@@ -0,0 +1,40 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v2"
+
+	"github.com/prometheus-community/stackdriver_exporter/config"
+)
+
+type prefixConfigFile struct {
+	Prefixes []config.PrefixConfig `yaml:"prefixes"`
+}
+
+// loadPrefixConfigFile parses a YAML file holding per-prefix settings.
+func loadPrefixConfigFile(filename string) ([]config.PrefixConfig, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var f prefixConfigFile
+	if err := yaml.UnmarshalStrict(content, &f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+	return f.Prefixes, nil
+}
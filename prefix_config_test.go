@@ -0,0 +1,66 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus-community/stackdriver_exporter/relabel"
+)
+
+func writePrefixConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prefixes.yml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrefixConfigFile(t *testing.T) {
+	t.Parallel()
+
+	path := writePrefixConfig(t, `
+prefixes:
+  - prefix: compute.googleapis.com/instance
+    metric_relabel_configs:
+      - action: labeldrop
+        regex: instance_id
+`)
+	got, err := loadPrefixConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadPrefixConfigFile() err = %v", err)
+	}
+	if len(got) != 1 || got[0].Prefix != "compute.googleapis.com/instance" {
+		t.Fatalf("loadPrefixConfigFile() = %+v", got)
+	}
+	if len(got[0].MetricRelabelConfigs) != 1 || got[0].MetricRelabelConfigs[0].Action != relabel.LabelDrop {
+		t.Fatalf("metric_relabel_configs = %+v", got[0].MetricRelabelConfigs)
+	}
+}
+
+func TestLoadPrefixConfigFileRejectsUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	path := writePrefixConfig(t, `
+prefixes:
+  - prefix: compute.googleapis.com/instance
+    not_a_field: true
+`)
+	if _, err := loadPrefixConfigFile(path); err == nil {
+		t.Fatal("expected error for unknown field, got nil")
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package relabel implements the subset of Prometheus relabeling that is
+// useful before exposition: keep, drop, replace, hashmod, labelmap, labeldrop
+// and labelkeep. Semantics follow
+// https://prometheus.io/docs/prometheus/latest/configuration/configuration/#relabel_config
+package relabel
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Action is the relabeling action to be performed.
+type Action string
+
+const (
+	// Replace performs a regex replacement.
+	Replace Action = "replace"
+	// Keep drops series for which the regex does not match the source labels.
+	Keep Action = "keep"
+	// Drop drops series for which the regex matches the source labels.
+	Drop Action = "drop"
+	// HashMod sets a label to the modulus of a hash of the source labels.
+	HashMod Action = "hashmod"
+	// LabelMap copies labels to other label names based on the regex.
+	LabelMap Action = "labelmap"
+	// LabelDrop drops any label matching the regex.
+	LabelDrop Action = "labeldrop"
+	// LabelKeep drops any label not matching the regex.
+	LabelKeep Action = "labelkeep"
+)
+
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// DefaultConfig is the default relabel configuration, matching the
+// Prometheus defaults.
+var DefaultConfig = Config{
+	Action:      Replace,
+	Separator:   ";",
+	Regex:       MustNewRegexp("(.*)"),
+	Replacement: "$1",
+}
+
+// Config is a single relabeling rule.
+type Config struct {
+	// SourceLabels are the labels whose values are concatenated and matched against Regex.
+	SourceLabels []string `yaml:"source_labels,flow,omitempty"`
+	// Separator is placed between concatenated source label values.
+	Separator string `yaml:"separator,omitempty"`
+	// Regex is matched against the concatenated source label values, or the label names for labelmap,
+	// labeldrop and labelkeep.
+	Regex Regexp `yaml:"regex,omitempty"`
+	// Modulus is the modulus used by hashmod.
+	Modulus uint64 `yaml:"modulus,omitempty"`
+	// TargetLabel is the label written by replace and hashmod.
+	TargetLabel string `yaml:"target_label,omitempty"`
+	// Replacement is the value written by replace, expanded with the regex capture groups.
+	Replacement string `yaml:"replacement,omitempty"`
+	// Action is the action to perform.
+	Action Action `yaml:"action,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler and applies the defaults.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate reports whether the rule can be applied.
+func (c *Config) Validate() error {
+	if c.Regex.Regexp == nil {
+		return fmt.Errorf("relabel configuration for %s action requires a regex", c.Action)
+	}
+	switch c.Action {
+	case Replace:
+		if c.TargetLabel == "" {
+			return fmt.Errorf("relabel configuration for replace action requires 'target_label' value")
+		}
+	case HashMod:
+		if c.TargetLabel == "" {
+			return fmt.Errorf("relabel configuration for hashmod action requires 'target_label' value")
+		}
+		if c.Modulus == 0 {
+			return fmt.Errorf("relabel configuration for hashmod requires non-zero modulus")
+		}
+		if !labelNameRE.MatchString(c.TargetLabel) {
+			return fmt.Errorf("%q is invalid 'target_label' for hashmod action", c.TargetLabel)
+		}
+	case Keep, Drop:
+		if len(c.SourceLabels) == 0 {
+			return fmt.Errorf("relabel configuration for %s action requires 'source_labels' value", c.Action)
+		}
+	case LabelMap, LabelDrop, LabelKeep:
+	default:
+		return fmt.Errorf("unknown relabel action %q", c.Action)
+	}
+	return nil
+}
+
+// Regexp encapsulates a regexp.Regexp and makes it YAML unmarshalable. The
+// expression is fully anchored, as in Prometheus.
+type Regexp struct {
+	*regexp.Regexp
+	original string
+}
+
+// NewRegexp creates a new anchored Regexp.
+func NewRegexp(s string) (Regexp, error) {
+	re, err := regexp.Compile("^(?s:" + s + ")$")
+	return Regexp{Regexp: re, original: s}, err
+}
+
+// MustNewRegexp works like NewRegexp, but panics if the regular expression does not compile.
+func MustNewRegexp(s string) Regexp {
+	re, err := NewRegexp(s)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (re *Regexp) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	r, err := NewRegexp(s)
+	if err != nil {
+		return err
+	}
+	*re = r
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (re Regexp) MarshalYAML() (interface{}, error) {
+	if re.Regexp == nil {
+		return nil, nil
+	}
+	return re.original, nil
+}
+
+// String returns the original, unanchored expression.
+func (re Regexp) String() string {
+	return re.original
+}
+
+// Process applies the rules in order to the label set given as parallel
+// key/value slices. It returns the resulting label set, preserving the order
+// of surviving labels and appending new ones, and false if the series should
+// be dropped. The input slices are not modified.
+func Process(keys, values []string, cfgs ...*Config) ([]string, []string, bool) {
+	lb := newBuilder(keys, values)
+	for _, cfg := range cfgs {
+		if !lb.relabel(cfg) {
+			return nil, nil, false
+		}
+	}
+	outKeys, outValues := lb.labels()
+	return outKeys, outValues, true
+}
+
+// builder is an ordered, mutable label set.
+type builder struct {
+	keys   []string
+	values map[string]string
+}
+
+func newBuilder(keys, values []string) *builder {
+	b := &builder{
+		keys:   make([]string, 0, len(keys)),
+		values: make(map[string]string, len(keys)),
+	}
+	for i, key := range keys {
+		b.set(key, values[i])
+	}
+	return b
+}
+
+func (b *builder) get(key string) string {
+	return b.values[key]
+}
+
+func (b *builder) set(key, value string) {
+	if _, ok := b.values[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.values[key] = value
+}
+
+func (b *builder) del(key string) {
+	if _, ok := b.values[key]; !ok {
+		return
+	}
+	delete(b.values, key)
+	for i, k := range b.keys {
+		if k == key {
+			b.keys = append(b.keys[:i], b.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (b *builder) labels() ([]string, []string) {
+	keys := make([]string, 0, len(b.keys))
+	values := make([]string, 0, len(b.keys))
+	for _, key := range b.keys {
+		keys = append(keys, key)
+		values = append(values, b.values[key])
+	}
+	return keys, values
+}
+
+func (b *builder) relabel(cfg *Config) bool {
+	values := make([]string, 0, len(cfg.SourceLabels))
+	for _, ln := range cfg.SourceLabels {
+		values = append(values, b.get(ln))
+	}
+	val := strings.Join(values, cfg.Separator)
+
+	switch cfg.Action {
+	case Drop:
+		if cfg.Regex.MatchString(val) {
+			return false
+		}
+	case Keep:
+		if !cfg.Regex.MatchString(val) {
+			return false
+		}
+	case Replace:
+		indexes := cfg.Regex.FindStringSubmatchIndex(val)
+		// If there is no match no replacement must take place.
+		if indexes == nil {
+			break
+		}
+		target := string(cfg.Regex.ExpandString([]byte{}, cfg.TargetLabel, val, indexes))
+		if !labelNameRE.MatchString(target) {
+			break
+		}
+		res := cfg.Regex.ExpandString([]byte{}, cfg.Replacement, val, indexes)
+		if len(res) == 0 {
+			b.del(target)
+			break
+		}
+		b.set(target, string(res))
+	case HashMod:
+		sum := md5.Sum([]byte(val))
+		// Use only the last 8 bytes of the hash to match Prometheus.
+		mod := binary.BigEndian.Uint64(sum[8:]) % cfg.Modulus
+		b.set(cfg.TargetLabel, fmt.Sprintf("%d", mod))
+	case LabelMap:
+		for _, key := range append([]string{}, b.keys...) {
+			if cfg.Regex.MatchString(key) {
+				res := cfg.Regex.ReplaceAllString(key, cfg.Replacement)
+				b.set(res, b.get(key))
+			}
+		}
+	case LabelDrop:
+		for _, key := range append([]string{}, b.keys...) {
+			if cfg.Regex.MatchString(key) {
+				b.del(key)
+			}
+		}
+	case LabelKeep:
+		for _, key := range append([]string{}, b.keys...) {
+			if !cfg.Regex.MatchString(key) {
+				b.del(key)
+			}
+		}
+	default:
+		panic(fmt.Errorf("relabel: unknown relabel action type %q", cfg.Action))
+	}
+	return true
+}
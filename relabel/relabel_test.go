@@ -0,0 +1,196 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relabel
+
+import (
+	"reflect"
+	"testing"
+
+	"go.yaml.in/yaml/v2"
+)
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"unit", "instance_id", "zone", "instance_name"}
+	values := []string{"By", "1234", "us-east1-b", "web-1"}
+
+	tests := []struct {
+		name       string
+		cfgs       []*Config
+		wantKeys   []string
+		wantValues []string
+		wantKeep   bool
+	}{
+		{
+			name:       "no rules",
+			wantKeys:   keys,
+			wantValues: values,
+			wantKeep:   true,
+		},
+		{
+			name:       "labeldrop",
+			cfgs:       []*Config{{Action: LabelDrop, Regex: MustNewRegexp("instance_id|unit")}},
+			wantKeys:   []string{"zone", "instance_name"},
+			wantValues: []string{"us-east1-b", "web-1"},
+			wantKeep:   true,
+		},
+		{
+			name:       "labelkeep",
+			cfgs:       []*Config{{Action: LabelKeep, Regex: MustNewRegexp("zone")}},
+			wantKeys:   []string{"zone"},
+			wantValues: []string{"us-east1-b"},
+			wantKeep:   true,
+		},
+		{
+			name:       "keep matching",
+			cfgs:       []*Config{{Action: Keep, SourceLabels: []string{"zone"}, Separator: ";", Regex: MustNewRegexp("us-east1-.*")}},
+			wantKeys:   keys,
+			wantValues: values,
+			wantKeep:   true,
+		},
+		{
+			name:     "keep not matching",
+			cfgs:     []*Config{{Action: Keep, SourceLabels: []string{"zone"}, Separator: ";", Regex: MustNewRegexp("europe-.*")}},
+			wantKeep: false,
+		},
+		{
+			name:     "drop matching",
+			cfgs:     []*Config{{Action: Drop, SourceLabels: []string{"instance_name", "zone"}, Separator: ";", Regex: MustNewRegexp("web-1;us-.*")}},
+			wantKeep: false,
+		},
+		{
+			name: "replace into new label",
+			cfgs: []*Config{{
+				Action:       Replace,
+				SourceLabels: []string{"zone"},
+				Separator:    ";",
+				Regex:        MustNewRegexp("(.*)-[a-z]"),
+				TargetLabel:  "region",
+				Replacement:  "$1",
+			}},
+			wantKeys:   []string{"unit", "instance_id", "zone", "instance_name", "region"},
+			wantValues: []string{"By", "1234", "us-east1-b", "web-1", "us-east1"},
+			wantKeep:   true,
+		},
+		{
+			name: "replace with empty value deletes label",
+			cfgs: []*Config{{
+				Action:      Replace,
+				Separator:   ";",
+				Regex:       MustNewRegexp("(.*)"),
+				TargetLabel: "instance_id",
+				Replacement: "",
+			}},
+			wantKeys:   []string{"unit", "zone", "instance_name"},
+			wantValues: []string{"By", "us-east1-b", "web-1"},
+			wantKeep:   true,
+		},
+		{
+			name:       "labelmap",
+			cfgs:       []*Config{{Action: LabelMap, Regex: MustNewRegexp("instance_(.*)"), Replacement: "vm_$1"}},
+			wantKeys:   []string{"unit", "instance_id", "zone", "instance_name", "vm_id", "vm_name"},
+			wantValues: []string{"By", "1234", "us-east1-b", "web-1", "1234", "web-1"},
+			wantKeep:   true,
+		},
+		{
+			name: "rules apply in order",
+			cfgs: []*Config{
+				{Action: LabelMap, Regex: MustNewRegexp("instance_name"), Replacement: "name"},
+				{Action: LabelDrop, Regex: MustNewRegexp("instance_.*")},
+			},
+			wantKeys:   []string{"unit", "zone", "name"},
+			wantValues: []string{"By", "us-east1-b", "web-1"},
+			wantKeep:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotKeys, gotValues, gotKeep := Process(keys, values, tt.cfgs...)
+			if gotKeep != tt.wantKeep {
+				t.Fatalf("Process() keep = %v, want %v", gotKeep, tt.wantKeep)
+			}
+			if !tt.wantKeep {
+				return
+			}
+			if !reflect.DeepEqual(gotKeys, tt.wantKeys) || !reflect.DeepEqual(gotValues, tt.wantValues) {
+				t.Fatalf("Process() = (%v, %v), want (%v, %v)", gotKeys, gotValues, tt.wantKeys, tt.wantValues)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(keys, []string{"unit", "instance_id", "zone", "instance_name"}) {
+		t.Fatalf("Process() mutated input keys = %v", keys)
+	}
+}
+
+func TestProcessHashMod(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{Action: HashMod, SourceLabels: []string{"instance_id"}, Separator: ";", Regex: MustNewRegexp("(.*)"), TargetLabel: "shard", Modulus: 4}
+	first, firstValues, _ := Process([]string{"instance_id"}, []string{"1234"}, cfg)
+	_, secondValues, _ := Process([]string{"instance_id"}, []string{"1234"}, cfg)
+
+	if !reflect.DeepEqual(first, []string{"instance_id", "shard"}) {
+		t.Fatalf("Process() keys = %v, want shard label appended", first)
+	}
+	if firstValues[1] != secondValues[1] {
+		t.Fatalf("hashmod is not deterministic: %q vs %q", firstValues[1], secondValues[1])
+	}
+	if firstValues[1] < "0" || firstValues[1] > "3" {
+		t.Fatalf("hashmod value %q out of range for modulus 4", firstValues[1])
+	}
+}
+
+func TestConfigUnmarshalYAML(t *testing.T) {
+	t.Parallel()
+
+	var cfgs []*Config
+	input := `
+- action: labeldrop
+  regex: instance_id
+- source_labels: [zone]
+  target_label: region
+  regex: (.*)-[a-z]
+`
+	if err := yaml.UnmarshalStrict([]byte(input), &cfgs); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(cfgs) != 2 {
+		t.Fatalf("got %d configs, want 2", len(cfgs))
+	}
+	if cfgs[0].Action != LabelDrop || cfgs[0].Regex.String() != "instance_id" {
+		t.Errorf("first config = %+v", cfgs[0])
+	}
+	if cfgs[1].Action != Replace || cfgs[1].Replacement != "$1" || cfgs[1].Separator != ";" {
+		t.Errorf("second config did not get defaults: %+v", cfgs[1])
+	}
+
+	invalid := []string{
+		"- action: replace\n",
+		"- action: hashmod\n  target_label: shard\n",
+		"- action: keep\n",
+		"- action: unknown\n",
+		"- action: labeldrop\n  regex: '('\n",
+	}
+	for _, in := range invalid {
+		var cfgs []*Config
+		if err := yaml.UnmarshalStrict([]byte(in), &cfgs); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
@@ -19,9 +19,12 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,6 +35,7 @@ import (
 	"github.com/prometheus/common/version"
 	"github.com/prometheus/exporter-toolkit/web"
 	webflag "github.com/prometheus/exporter-toolkit/web/kingpinflag"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/prometheus-community/stackdriver_exporter/collectors"
 	"github.com/prometheus-community/stackdriver_exporter/config"
@@ -63,6 +67,26 @@ var (
 		"google.projects.filter", "Google projects search filter.",
 	).String()
 
+	projectsParents = kingpin.Flag(
+		"google.projects.parents", "Repeatable flag of folders/<id> or organizations/<id> whose projects are scraped, walking their folders recursively.",
+	).Strings()
+
+	projectsExcludeLabels = kingpin.Flag(
+		"google.projects.exclude-labels", "Repeatable flag of project labels, as key or key=value, excluding projects found below google.projects.parents.",
+	).Strings()
+
+	projectsExcludeRegexes = kingpin.Flag(
+		"google.projects.exclude-regexes", "Repeatable flag of regular expressions matching the full ID of projects found below google.projects.parents to exclude.",
+	).Strings()
+
+	projectsExcludeStates = kingpin.Flag(
+		"google.projects.exclude-states", "Repeatable flag of lifecycle states excluding projects found below google.projects.parents.",
+	).Default(config.DefaultProjectsExcludeStates...).Strings()
+
+	projectsRefreshInterval = kingpin.Flag(
+		"google.projects.refresh-interval", "Interval at which the projects matching google.projects.filter are resolved again, 0 disables the refresh.",
+	).Default("0s").Duration()
+
 	googleUniverseDomain = kingpin.Flag(
 		"google.universe-domain", "The Cloud universe to use.",
 	).Default(config.DefaultUniverseDomain).String()
@@ -96,6 +120,38 @@ var (
 		"monitoring.metrics-prefixes", "Google Stackdriver Monitoring Metric Type prefixes. Repeat this flag to scrape multiple prefixes.",
 	).Strings()
 
+	monitoringMetricsExcludePrefixes = kingpin.Flag(
+		"monitoring.metrics-exclude-prefixes", "Google Stackdriver Monitoring Metric Type prefixes to skip. Repeat this flag to exclude multiple prefixes.",
+	).Strings()
+
+	monitoringMetricsExcludeRegexes = kingpin.Flag(
+		"monitoring.metrics-exclude-regexes", "Regular expressions matching the full Metric Type of descriptors to skip. Repeat this flag to exclude multiple patterns.",
+	).Strings()
+
+	monitoringIncludeLaunchStages = kingpin.Flag(
+		"monitoring.include-launch-stages", "Only scrape metric descriptors with one of these launch stages, i.e: GA. Repeatable.",
+	).Strings()
+
+	monitoringExcludeLaunchStages = kingpin.Flag(
+		"monitoring.exclude-launch-stages", "Skip metric descriptors with one of these launch stages, i.e: DEPRECATED. Repeatable.",
+	).Strings()
+
+	monitoringIncludeMetricKinds = kingpin.Flag(
+		"monitoring.include-metric-kinds", "Only scrape metric descriptors of one of these metric kinds (GAUGE, DELTA, CUMULATIVE). Repeatable.",
+	).Strings()
+
+	monitoringExcludeMetricKinds = kingpin.Flag(
+		"monitoring.exclude-metric-kinds", "Skip metric descriptors of one of these metric kinds (GAUGE, DELTA, CUMULATIVE). Repeatable.",
+	).Strings()
+
+	monitoringIncludeValueTypes = kingpin.Flag(
+		"monitoring.include-value-types", "Only scrape metric descriptors of one of these value types (BOOL, INT64, DOUBLE, DISTRIBUTION). Repeatable.",
+	).Strings()
+
+	monitoringExcludeValueTypes = kingpin.Flag(
+		"monitoring.exclude-value-types", "Skip metric descriptors of one of these value types (BOOL, INT64, DOUBLE, DISTRIBUTION). Repeatable.",
+	).Strings()
+
 	monitoringMetricsInterval = kingpin.Flag(
 		"monitoring.metrics-interval", "Interval to request the Google Stackdriver Monitoring Metrics for. Only the most recent data point is used.",
 	).Default(config.DefaultMetricsInterval.String()).Duration()
@@ -116,6 +172,42 @@ var (
 		"monitoring.drop-delegated-projects", "Drop metrics from attached projects and fetch `project_id` only.",
 	).Default(strconv.FormatBool(config.DefaultDropDelegated)).Bool()
 
+	monitoringDeduplicateProjects = kingpin.Flag(
+		"monitoring.dedupe-projects", "Export the series of each monitored project from a single project's collector when metrics scopes overlap.",
+	).Default(strconv.FormatBool(config.DefaultDeduplicateProjects)).Bool()
+
+	monitoringOwnershipClaimTTL = kingpin.Flag(
+		"monitoring.dedupe-projects.claim-ttl", "How long a collector keeps exporting a monitored project of another collector's metrics scope after it last saw it.",
+	).Default(config.DefaultOwnershipClaimTTL.String()).Duration()
+
+	monitoringScopingProject = kingpin.Flag(
+		"monitoring.scoping-project", "Query only this scoping project for the projects of its metrics scope. Other projects are queried on their own.",
+	).String()
+
+	monitoringTargetParents = kingpin.Flag(
+		"monitoring.target-parents", "Repeatable flag of folders/<id> or organizations/<id> whose time series are listed with a single call per metric type.",
+	).Strings()
+
+	monitoringTargetDescriptorProject = kingpin.Flag(
+		"monitoring.target-descriptor-project", "Project metric descriptors are listed from for monitoring.target-parents. Defaults to the project of the credentials.",
+	).String()
+
+	monitoringProjectEnrichment = kingpin.Flag(
+		"monitoring.project-enrichment", "Fill project_id from the collector when missing, replace project numbers with IDs and add project metadata labels.",
+	).Default(strconv.FormatBool(config.DefaultProjectEnrichment)).Bool()
+
+	monitoringProjectEnrichmentFields = kingpin.Flag(
+		"monitoring.project-enrichment-fields", "Repeatable flag of project fields added as project_<field> labels with monitoring.project-enrichment.",
+	).Enums(config.ProjectFields...)
+
+	monitoringProjectEnrichmentLabels = kingpin.Flag(
+		"monitoring.project-enrichment-labels", "Repeatable flag of project label keys added as project_label_<key> labels with monitoring.project-enrichment.",
+	).Strings()
+
+	monitoringProjectEnrichmentTTL = kingpin.Flag(
+		"monitoring.project-enrichment-ttl", "How long fetched project metadata is cached.",
+	).Default(config.DefaultProjectEnrichmentTTL.String()).Duration()
+
 	monitoringMetricsExtraFilter = kingpin.Flag(
 		"monitoring.filters",
 		"Filters. i.e: pubsub.googleapis.com/subscription:resource.labels.subscription_id=monitoring.regex.full_match(\"my-subs-prefix.*\")",
@@ -129,6 +221,42 @@ var (
 		"monitoring.aggregate-deltas-ttl", "How long should a delta metric continue to be exported after GCP stops producing a metric",
 	).Default(config.DefaultDeltasTTL.String()).Duration()
 
+	monitoringMetricsDeltasZeroSeed = kingpin.Flag(
+		"monitoring.aggregate-deltas-zero-seed", "If enabled, a new aggregated DELTA series is first reported as 0 at the start of its first point, so rate() counts that point.",
+	).Default(strconv.FormatBool(config.DefaultDeltasZeroSeed)).Bool()
+
+	monitoringDeltaStore = kingpin.Flag(
+		"monitoring.aggregate-deltas-store", "Where aggregated DELTA metrics are kept: memory, snapshotted to a file or a bbolt database to survive restarts, or redis to share them between replicas.",
+	).Default(config.DefaultDeltaStore).Enum(config.DeltaStores...)
+
+	monitoringDeltaStorePath = kingpin.Flag(
+		"monitoring.aggregate-deltas-store-path", "Path of the file or bbolt database of monitoring.aggregate-deltas-store.",
+	).String()
+
+	monitoringDeltaSnapshotInterval = kingpin.Flag(
+		"monitoring.aggregate-deltas-snapshot-interval", "Interval at which aggregated DELTA metrics are snapshotted with a file or bbolt store.",
+	).Default(config.DefaultDeltaStoreSnapshot.String()).Duration()
+
+	monitoringDeltaStoreMaxSeries = kingpin.Flag(
+		"monitoring.aggregate-deltas-max-series", "Maximum number of aggregated DELTA counters, and of histograms, kept by the memory, file and bolt stores, 0 for unlimited. The least recently incremented series are evicted first.",
+	).Default(strconv.Itoa(config.DefaultDeltaStoreMaxSeries)).Int()
+
+	monitoringDeltaSweepInterval = kingpin.Flag(
+		"monitoring.aggregate-deltas-sweep-interval", "Interval at which aggregated DELTA series outside of monitoring.aggregate-deltas-ttl are removed from the memory, file and bolt stores, 0 to disable.",
+	).Default(config.DefaultDeltaSweepInterval.String()).Duration()
+
+	monitoringDeltaBucketChange = kingpin.Flag(
+		"monitoring.aggregate-deltas-bucket-change", "What to do when the bucket bounds of an aggregated DELTA distribution change: reset the histogram, or rebucket it onto the new bounds. The redis store always resets.",
+	).Default(config.DefaultDeltaBucketChange).Enum(config.BucketChanges...)
+
+	monitoringDeltaRedisURL = kingpin.Flag(
+		"monitoring.aggregate-deltas-redis-url", "URL of the Redis server of the redis monitoring.aggregate-deltas-store, such as redis://host:6379/0.",
+	).String()
+
+	monitoringDeltaRedisPrefix = kingpin.Flag(
+		"monitoring.aggregate-deltas-redis-prefix", "Prefix of the keys of the redis monitoring.aggregate-deltas-store. Replicas sharing counters must use the same prefix.",
+	).Default(config.DefaultDeltaRedisPrefix).String()
+
 	monitoringDescriptorCacheTTL = kingpin.Flag(
 		"monitoring.descriptor-cache-ttl", "How long should the metric descriptors for a prefixed be cached for",
 	).Default(config.DefaultDescriptorTTL.String()).Duration()
@@ -136,6 +264,30 @@ var (
 	monitoringDescriptorCacheOnlyGoogle = kingpin.Flag(
 		"monitoring.descriptor-cache-only-google", "Only cache descriptors for *.googleapis.com metrics",
 	).Default(strconv.FormatBool(config.DefaultDescriptorGoogleOnly)).Bool()
+
+	monitoringLabelCollisionStrategy = kingpin.Flag(
+		"monitoring.label-collision-strategy", "What to do when a monitored resource label has the same key as a metric label: drop the resource label, prefix-resource, prefix-metric or error.",
+	).Default(config.DefaultLabelCollisionStrategy).Enum(config.LabelCollisionStrategies...)
+
+	monitoringMaxSeriesPerMetric = kingpin.Flag(
+		"monitoring.max-series-per-metric", "Maximum number of series exported per metric type in a scrape, 0 for unlimited. Series over the limit are dropped by a stable hash of their labels.",
+	).Default(strconv.Itoa(config.DefaultMaxSeriesPerMetric)).Int()
+
+	monitoringDescriptorInfoMetric = kingpin.Flag(
+		"monitoring.descriptor-info-metric", "Export a stackdriver_metric_descriptor_info metric for each scraped metric descriptor.",
+	).Default(strconv.FormatBool(config.DefaultDescriptorInfoMetric)).Bool()
+
+	monitoringHelpIncludeMetricType = kingpin.Flag(
+		"monitoring.help-include-metric-type", "Append the original Google Cloud metric type to the HELP text of exported metrics.",
+	).Default(strconv.FormatBool(config.DefaultHelpIncludeMetricType)).Bool()
+
+	monitoringCardinalityStats = kingpin.Flag(
+		"monitoring.cardinality-stats", "Record the series count and API calls of each scrape and serve them at "+cardinalityPath+".",
+	).Default(strconv.FormatBool(config.DefaultCardinalityStats)).Bool()
+
+	monitoringPrefixConfigFile = kingpin.Flag(
+		"monitoring.prefix-config-file", "Path to a YAML file with per metric type prefix settings such as metric_relabel_configs.",
+	).String()
 )
 
 func init() {
@@ -177,15 +329,9 @@ func newHandler(runtime *collectors.Runtime, logger *slog.Logger, additionalGath
 		additionalGatherer: additionalGatherer,
 	}
 
-	cs, err := runtime.Collectors()
-	if err != nil {
-		return nil, fmt.Errorf("build collectors: %w", err)
-	}
 	registry := prometheus.NewRegistry()
-	for _, c := range cs {
-		if err := registry.Register(c); err != nil {
-			return nil, fmt.Errorf("register collector: %w", err)
-		}
+	if err := registry.Register(runtime.ProjectsCollector()); err != nil {
+		return nil, fmt.Errorf("register collector: %w", err)
 	}
 	h.handler = h.handlerFor(registry)
 	return h, nil
@@ -246,6 +392,14 @@ func main() {
 	if *monitoringMetricsTypePrefixes != "" {
 		cfg.MetricsPrefixes = append(cfg.MetricsPrefixes, strings.Split(*monitoringMetricsTypePrefixes, ",")...)
 	}
+	if *monitoringPrefixConfigFile != "" {
+		prefixConfigs, err := loadPrefixConfigFile(*monitoringPrefixConfigFile)
+		if err != nil {
+			logger.Error("failed to load prefix config file", "err", err)
+			os.Exit(1)
+		}
+		cfg.PrefixConfigs = prefixConfigs
+	}
 
 	logger.Info(
 		"Starting stackdriver_exporter",
@@ -255,6 +409,7 @@ func main() {
 		"extra_filters", strings.Join(cfg.Filters, ","),
 		"projectIDs", fmt.Sprintf("%v", cfg.ProjectIDs),
 		"projectsFilter", cfg.ProjectsFilter,
+		"projectsParents", fmt.Sprintf("%v", cfg.ProjectsParents),
 	)
 
 	if err := cfg.Validate(); err != nil {
@@ -262,12 +417,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	runtime, err := collectors.NewRuntime(ctx, logger, cfg, delta.NewInMemoryCounterStore, delta.NewInMemoryHistogramStore)
+	counterFactory, histogramFactory, err := deltaStoreFactories(ctx, logger, cfg)
+	if err != nil {
+		logger.Error("failed to open delta store", "err", err)
+		os.Exit(1)
+	}
+
+	runtime, err := collectors.NewRuntime(ctx, logger, cfg, counterFactory, histogramFactory)
 	if err != nil {
 		logger.Error("failed to initialize", "err", err)
 		os.Exit(1)
 	}
 	runtime = runtime.WithCache()
+	prometheus.MustRegister(runtime.ProjectDiscoveryCollector(), runtime.DescriptorCacheCollector())
+	if cfg.ProjectsRefreshInterval > 0 {
+		go runtime.RunProjectRefresh(ctx, cfg.ProjectsRefreshInterval)
+	}
 
 	if *metricsPath == *stackdriverMetricsPath {
 		h, err := newHandler(runtime, logger, prometheus.DefaultGatherer)
@@ -287,6 +452,10 @@ func main() {
 		http.Handle(*metricsPath, promhttp.Handler())
 	}
 
+	if cfg.CardinalityStats {
+		http.Handle(cardinalityPath, &cardinalityHandler{runtime: runtime, logger: logger})
+	}
+
 	if *metricsPath != "/" && *metricsPath != "" {
 		landingConfig := web.LandingConfig{
 			Name:        "Stackdriver Exporter",
@@ -307,6 +476,14 @@ func main() {
 				},
 			)
 		}
+		if cfg.CardinalityStats {
+			landingConfig.Links = append(landingConfig.Links,
+				web.LandingLinks{
+					Address: cardinalityPath,
+					Text:    "Cardinality",
+				},
+			)
+		}
 		landingPage, err := web.NewLandingPage(landingConfig)
 		if err != nil {
 			logger.Error("error creating landing page", "err", err)
@@ -322,10 +499,88 @@ func main() {
 	}
 }
 
+// deltaStoreFactories returns the delta store factories for the configured
+// store. In-memory and persistent stores are swept in the background and
+// register their series metrics. Persistent stores are also snapshotted in the
+// background and flushed when the exporter is interrupted or terminated.
+func deltaStoreFactories(ctx context.Context, logger *slog.Logger, cfg *config.Config) (collectors.CounterStoreFactory, collectors.HistogramStoreFactory, error) {
+	opts := delta.StoreOptions{
+		ZeroSeed:     cfg.AggregateDeltasZeroSeed,
+		MaxSeries:    cfg.DeltaStoreMaxSeries,
+		BucketChange: cfg.DeltaBucketChange,
+	}
+	var backend delta.SnapshotBackend
+	switch cfg.DeltaStore {
+	case config.DeltaStoreFile:
+		backend = delta.NewFileBackend(cfg.DeltaStorePath)
+	case config.DeltaStoreBolt:
+		b, err := delta.NewBoltBackend(cfg.DeltaStorePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		backend = b
+	case config.DeltaStoreRedis:
+		redisOpts, err := redis.ParseURL(cfg.DeltaRedisURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid redis URL: %w", err)
+		}
+		client := redis.NewClient(redisOpts)
+		if err := client.Ping(ctx).Err(); err != nil {
+			return nil, nil, fmt.Errorf("connecting to redis: %w", err)
+		}
+		// The stores keep nothing but their reset counts in memory, so one of each serves every collector.
+		counters := delta.NewRedisCounterStore(client, cfg.DeltaRedisPrefix, logger, cfg.AggregateDeltasTTL)
+		histograms := delta.NewRedisHistogramStore(client, cfg.DeltaRedisPrefix, logger, cfg.AggregateDeltasTTL).(*delta.RedisHistogramStore)
+		prometheus.MustRegister(histograms)
+		counterFactory := func(*slog.Logger, time.Duration) collectors.DeltaCounterStore { return counters }
+		histogramFactory := func(*slog.Logger, time.Duration) collectors.DeltaHistogramStore { return histograms }
+		return counterFactory, histogramFactory, nil
+	default:
+		// Entries are keyed by metric descriptor name, which collectors set to their target and the descriptor
+		// type, so the stores are shared by all collectors and swept together.
+		counters := delta.NewInMemoryCounterStoreWithOptions(logger, cfg.AggregateDeltasTTL, opts).(*delta.InMemoryCounterStore)
+		histograms := delta.NewInMemoryHistogramStoreWithOptions(logger, cfg.AggregateDeltasTTL, opts).(*delta.InMemoryHistogramStore)
+		prometheus.MustRegister(counters, histograms)
+		if cfg.DeltaSweepInterval > 0 {
+			go delta.RunSweeper(ctx, cfg.DeltaSweepInterval, counters, histograms)
+		}
+		counterFactory := func(*slog.Logger, time.Duration) collectors.DeltaCounterStore { return counters }
+		histogramFactory := func(*slog.Logger, time.Duration) collectors.DeltaHistogramStore { return histograms }
+		return counterFactory, histogramFactory, nil
+	}
+
+	store, err := delta.NewPersistentStore(logger, backend, cfg.AggregateDeltasTTL, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	prometheus.MustRegister(store)
+	if cfg.DeltaSweepInterval > 0 {
+		go delta.RunSweeper(ctx, cfg.DeltaSweepInterval, store)
+	}
+	go store.Run(ctx, cfg.DeltaSnapshotInterval)
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		logger.Info("flushing delta store before exiting")
+		if err := store.Close(); err != nil {
+			logger.Error("error flushing delta store", "err", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}()
+	return store.CounterStoreFactory, store.HistogramStoreFactory, nil
+}
+
 func collectorConfigFromFlags() *config.Config {
 	return &config.Config{
 		ProjectIDs:                slices.Clone(*projectIDs),
 		ProjectsFilter:            *projectsFilter,
+		ProjectsRefreshInterval:   *projectsRefreshInterval,
+		ProjectsParents:           slices.Clone(*projectsParents),
+		ProjectsExcludeLabels:     slices.Clone(*projectsExcludeLabels),
+		ProjectsExcludeRegexes:    slices.Clone(*projectsExcludeRegexes),
+		ProjectsExcludeStates:     slices.Clone(*projectsExcludeStates),
 		UniverseDomain:            *googleUniverseDomain,
 		MaxRetries:                *stackdriverMaxRetries,
 		HTTPTimeout:               *stackdriverHttpTimeout,
@@ -333,16 +588,47 @@ func collectorConfigFromFlags() *config.Config {
 		BackoffJitter:             *stackdriverBackoffJitterBase,
 		RetryStatuses:             slices.Clone(*stackdriverRetryStatuses),
 		MetricsPrefixes:           slices.Clone(*monitoringMetricsPrefixes),
+		MetricsExcludePrefixes:    slices.Clone(*monitoringMetricsExcludePrefixes),
+		MetricsExcludeRegexes:     slices.Clone(*monitoringMetricsExcludeRegexes),
+		IncludeLaunchStages:       slices.Clone(*monitoringIncludeLaunchStages),
+		ExcludeLaunchStages:       slices.Clone(*monitoringExcludeLaunchStages),
+		IncludeMetricKinds:        slices.Clone(*monitoringIncludeMetricKinds),
+		ExcludeMetricKinds:        slices.Clone(*monitoringExcludeMetricKinds),
+		IncludeValueTypes:         slices.Clone(*monitoringIncludeValueTypes),
+		ExcludeValueTypes:         slices.Clone(*monitoringExcludeValueTypes),
 		MetricsInterval:           *monitoringMetricsInterval,
 		MetricsOffset:             *monitoringMetricsOffset,
 		MetricsIngestDelay:        *monitoringMetricsIngestDelay,
 		FillMissingLabels:         *collectorFillMissingLabels,
 		DropDelegatedProjects:     *monitoringDropDelegatedProjects,
+		DeduplicateProjects:       *monitoringDeduplicateProjects,
+		OwnershipClaimTTL:         *monitoringOwnershipClaimTTL,
+		ScopingProjectID:          *monitoringScopingProject,
+		TargetParents:             slices.Clone(*monitoringTargetParents),
+		TargetDescriptorProjectID: *monitoringTargetDescriptorProject,
+		ProjectEnrichment:         *monitoringProjectEnrichment,
+		ProjectEnrichmentFields:   slices.Clone(*monitoringProjectEnrichmentFields),
+		ProjectEnrichmentLabels:   slices.Clone(*monitoringProjectEnrichmentLabels),
+		ProjectEnrichmentTTL:      *monitoringProjectEnrichmentTTL,
 		Filters:                   slices.Clone(*monitoringMetricsExtraFilter),
 		AggregateDeltas:           *monitoringMetricsAggregateDeltas,
 		AggregateDeltasTTL:        *monitoringMetricsDeltasTTL,
+		AggregateDeltasZeroSeed:   *monitoringMetricsDeltasZeroSeed,
+		DeltaStore:                *monitoringDeltaStore,
+		DeltaStorePath:            *monitoringDeltaStorePath,
+		DeltaSnapshotInterval:     *monitoringDeltaSnapshotInterval,
+		DeltaRedisURL:             *monitoringDeltaRedisURL,
+		DeltaRedisPrefix:          *monitoringDeltaRedisPrefix,
+		DeltaStoreMaxSeries:       *monitoringDeltaStoreMaxSeries,
+		DeltaSweepInterval:        *monitoringDeltaSweepInterval,
+		DeltaBucketChange:         *monitoringDeltaBucketChange,
 		DescriptorCacheTTL:        *monitoringDescriptorCacheTTL,
 		DescriptorCacheOnlyGoogle: *monitoringDescriptorCacheOnlyGoogle,
+		LabelCollisionStrategy:    *monitoringLabelCollisionStrategy,
+		MaxSeriesPerMetric:        *monitoringMaxSeriesPerMetric,
+		DescriptorInfoMetric:      *monitoringDescriptorInfoMetric,
+		HelpIncludeMetricType:     *monitoringHelpIncludeMetricType,
+		CardinalityStats:          *monitoringCardinalityStats,
 	}
 }
 